@@ -0,0 +1,158 @@
+package aspace_xport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+// the record types that can be exported, also the segment of the record uris
+const (
+	ResourcesTarget  = "resources"
+	AccessionsTarget = "accessions"
+)
+
+const accessionsCSVFilename = "accessions.csv"
+
+var accessionsCSVHeader = []string{"repo_slug", "accession_id", "uri", "identifier", "title", "accession_date", "publish", "path", "size", "sha256", "status"}
+
+func CheckTarget(target string) error {
+	if target != ResourcesTarget && target != AccessionsTarget {
+		return fmt.Errorf("unsupported target %s, supported targets are `resources` or `accessions`", target)
+	}
+	return nil
+}
+
+// get a slice of ResourceInfo objects for the accessions in each repository, a non-zero modifiedSince only returns
+// accessions modified after that time
+func GetAccessionIDs(repMap map[string]int, modifiedSince time.Time) ([]ResourceInfo, error) {
+	accessions := []ResourceInfo{}
+
+	for repositorySlug, repositoryID := range repMap {
+		var accessionIDs []int
+		var err error
+		if modifiedSince.IsZero() {
+			accessionIDs, err = client.GetAccessionIDs(repositoryID)
+		} else {
+			accessionIDs, err = getModifiedIDs(repositoryID, AccessionsTarget, modifiedSince)
+		}
+		if err != nil {
+			return accessions, err
+		}
+
+		for _, accessionID := range accessionIDs {
+			accessions = append(accessions, ResourceInfo{
+				RepoID:     repositoryID,
+				RepoSlug:   repositorySlug,
+				ResourceID: accessionID,
+				RecordType: AccessionsTarget,
+			})
+		}
+	}
+
+	return accessions, nil
+}
+
+// export the json of an accession record as returned by archivesspace
+func exportAccession(ctx context.Context, info ResourceInfo, workerID int) ExportResult {
+	uri := info.URI()
+
+	//get the accession record
+	var accessionBytes []byte
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving accession %s", uri), func() error {
+		response, err := client.GetEndpoint(uri)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		accessionBytes, err = io.ReadAll(response.Body)
+		return err
+	})
+	if err != nil {
+		PrintAndLog(fmt.Sprintf("could not retrieve accession after %d attempts", attempts), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: uri, Error: err.Error()})
+		return ExportResult{Status: "ERROR", URI: uri, Error: err.Error(), Attempts: attempts}
+	}
+
+	accession := aspace.Accession{}
+	err = json.Unmarshal(accessionBytes, &accession)
+	if err != nil {
+		LogOnly("could not parse accession", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: uri, Error: err.Error()})
+		return ExportResult{Status: "ERROR", URI: uri, Error: err.Error(), Attempts: attempts}
+	}
+
+	result := ExportResult{
+		URI:        uri,
+		Title:      accession.Title,
+		Publish:    accession.Publish,
+		Identifier: MergeIDParts(accession.ID0, accession.ID1, accession.ID2, accession.ID3),
+		Date:       accession.AccessionDate,
+		Attempts:   attempts,
+	}
+
+	//write the accession json
+	formatResult := FormatResult{Format: ASPACEJSON}
+	accessionPath := filepath.Join(exportOptions.WorkDir, info.RepoSlug, AccessionsTarget, fmt.Sprintf("accession_%d.json", info.ResourceID))
+	err = os.WriteFile(accessionPath, accessionBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the accession %s", accessionPath), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: uri, Error: err.Error()})
+		formatResult.Status = "ERROR"
+		formatResult.Error = err.Error()
+	} else {
+		formatResult.Status = "SUCCESS"
+		formatResult.Path = accessionPath
+	}
+	result.Formats = []FormatResult{formatResult}
+
+	return aggregateResult(result)
+}
+
+// write a csv summary of the exported accessions to the work directory
+func createAccessionsCSV() error {
+	summary, err := os.Create(filepath.Join(exportOptions.WorkDir, accessionsCSVFilename))
+	if err != nil {
+		return err
+	}
+	defer summary.Close()
+
+	writer := csv.NewWriter(summary)
+	err = writer.Write(accessionsCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		formatResult := FormatResult{Status: result.Status}
+		if len(result.Formats) > 0 {
+			formatResult = result.Formats[0]
+		}
+
+		err = writer.Write([]string{
+			result.RepoSlug,
+			strconv.Itoa(result.ResourceID),
+			result.URI,
+			result.Identifier,
+			result.Title,
+			result.Date,
+			strconv.FormatBool(result.Publish),
+			formatResult.Path,
+			strconv.FormatInt(formatResult.Size, 10),
+			formatResult.Checksum,
+			result.Status,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,230 @@
+package aspace_xport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+const agentsDirectory = "agents"
+
+var agentResults []AgentResult
+
+// the archival_contexts endpoint for each agent type, by the agent type in the agent's uri
+var eacCPFEndpoints = map[string]string{
+	"people":             "people",
+	"corporate_entities": "corporate_entities",
+	"families":           "families",
+	"software":           "softwares",
+}
+
+// the result of exporting the eac-cpf record of an agent linked from one or more exported resources
+type AgentResult struct {
+	Status          string        `json:"status"`
+	URI             string        `json:"uri"`
+	RepoID          int           `json:"repo_id"` //the repository the eac-cpf was requested through
+	Resources       []string      `json:"resources"`
+	Path            string        `json:"path"`
+	Size            int64         `json:"size"`
+	Checksum        string        `json:"sha256"`
+	Error           string        `json:"error"`
+	Attempts        int           `json:"attempts"`
+	Duration        time.Duration `json:"-"`
+	DurationSeconds float64       `json:"duration_seconds"`
+}
+
+// the uris of the agents linked to a resource
+func linkedAgentURIs(res aspace.Resource) []string {
+	uris := []string{}
+	for _, agent := range res.LinkedAgents {
+		if agent.Ref != "" {
+			uris = append(uris, agent.Ref)
+		}
+	}
+	return uris
+}
+
+// the de-duplicated agents linked from the exported resources, in the order they were first linked
+func collectAgents() []AgentResult {
+	agents := []AgentResult{}
+	index := map[string]int{}
+	for _, result := range results {
+		if result.Status != "SUCCESS" && result.Status != "WARNING" {
+			continue
+		}
+		for _, uri := range result.Agents {
+			i, ok := index[uri]
+			if !ok {
+				i = len(agents)
+				index[uri] = i
+				agents = append(agents, AgentResult{URI: uri, RepoID: result.RepoID})
+			}
+			agents[i].Resources = append(agents[i].Resources, result.URI)
+		}
+	}
+	return agents
+}
+
+// the eac-cpf endpoint and output filename for an agent uri, e.g. /agents/people/12
+func eacCPFEndpoint(repoID int, agentURI string) (string, string, error) {
+	parts := strings.Split(strings.Trim(agentURI, "/"), "/")
+	if len(parts) != 3 || parts[0] != "agents" {
+		return "", "", fmt.Errorf("%s is not an agent uri", agentURI)
+	}
+
+	agentType, ok := eacCPFEndpoints[parts[1]]
+	if !ok {
+		return "", "", fmt.Errorf("eac-cpf is not available for agent type %s", parts[1])
+	}
+
+	endpoint := fmt.Sprintf("/repositories/%d/archival_contexts/%s/%s.xml", repoID, agentType, parts[2])
+	filename := fmt.Sprintf("%s_%s_eac.xml", parts[1], parts[2])
+	return endpoint, filename, nil
+}
+
+// export the eac-cpf of every agent linked from the exported resources with the same pool of workers, agents not
+// exported before ctx is cancelled are reported as CANCELLED
+func exportAgents(ctx context.Context) {
+	agents := collectAgents()
+	if len(agents) == 0 {
+		return
+	}
+	PrintAndLog(fmt.Sprintf("exporting eac-cpf for %d agents linked from exported resources", len(agents)), INFO)
+
+	prog := newProgress(len(agents), "agents")
+	stopProgress := prog.run()
+
+	agentChannel := make(chan AgentResult)
+	resultChannel := make(chan AgentResult)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= exportOptions.Workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for agent := range agentChannel {
+				if ctx.Err() != nil {
+					break
+				}
+				agentStart := time.Now()
+				result := exportAgent(ctx, agent, workerID)
+				result.Duration = time.Since(agentStart)
+				result.DurationSeconds = result.Duration.Seconds()
+				if ctx.Err() != nil && result.Status == "ERROR" {
+					break
+				}
+				prog.update(result.Status)
+				resultChannel <- result
+			}
+		}(i)
+	}
+
+	go func() {
+		defer close(agentChannel)
+		for _, agent := range agents {
+			select {
+			case <-ctx.Done():
+				return
+			case agentChannel <- agent:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(resultChannel)
+	}()
+
+	processed := map[string]bool{}
+	for result := range resultChannel {
+		agentResults = append(agentResults, result)
+		processed[result.URI] = true
+	}
+	stopProgress()
+
+	for _, agent := range agents {
+		if !processed[agent.URI] {
+			agent.Status = "CANCELLED"
+			agentResults = append(agentResults, agent)
+		}
+	}
+}
+
+func exportAgent(ctx context.Context, agent AgentResult, workerID int) AgentResult {
+	endpoint, filename, err := eacCPFEndpoint(agent.RepoID, agent.URI)
+	if err != nil {
+		LogOnly("could not export agent", ERROR, Fields{Worker: workerID, URI: agent.URI, Error: err.Error()})
+		agent.Status = "ERROR"
+		agent.Error = err.Error()
+		return agent
+	}
+
+	//get the eac-cpf record
+	var eacBytes []byte
+	agent.Attempts, err = withRetry(ctx, workerID, fmt.Sprintf("retrieving eac-cpf for %s", agent.URI), func() error {
+		response, err := client.GetEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		eacBytes, err = io.ReadAll(response.Body)
+		return err
+	})
+	if err != nil {
+		LogOnly("could not retrieve agent", ERROR, Fields{Worker: workerID, URI: agent.URI, Error: err.Error()})
+		agent.Status = "ERROR"
+		agent.Error = err.Error()
+		return agent
+	}
+
+	//write the eac-cpf file
+	agent.Path = filepath.Join(exportOptions.WorkDir, agentsDirectory, filename)
+	err = os.WriteFile(agent.Path, eacBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the eac-cpf record %s", agent.Path), ERROR, Fields{Worker: workerID, URI: agent.URI, Error: err.Error()})
+		agent.Status = "ERROR"
+		agent.Error = err.Error()
+		agent.Path = ""
+		return agent
+	}
+
+	agent.Size, agent.Checksum, err = fileChecksum(agent.Path)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not checksum %s", agent.Path), WARNING, Fields{Worker: workerID, URI: agent.URI, Error: err.Error()})
+	}
+
+	LogOnly("exported agent", INFO, Fields{Worker: workerID, URI: agent.URI})
+	agent.Status = "SUCCESS"
+	return agent
+}
+
+// the agents section of the text report
+func agentReport() string {
+	counts := map[string]int{}
+	errs := []AgentResult{}
+	for _, result := range agentResults {
+		counts[result.Status] = counts[result.Status] + 1
+		if result.Status == "ERROR" {
+			errs = append(errs, result)
+		}
+	}
+
+	msg := fmt.Sprintf("\n%d Agents linked from exported resources:\n", len(agentResults))
+	msg = msg + fmt.Sprintf("  %d EAC-CPF records exported\n", counts["SUCCESS"])
+	msg = msg + fmt.Sprintf("  %d Errors Encountered\n", counts["ERROR"])
+	for _, e := range errs {
+		msg = msg + fmt.Sprintf("    %s: %s\n", e.URI, strings.ReplaceAll(e.Error, "\n", " "))
+	}
+	if counts["CANCELLED"] > 0 {
+		msg = msg + fmt.Sprintf("  %d Agents cancelled before export\n", counts["CANCELLED"])
+	}
+	return msg
+}
@@ -0,0 +1,153 @@
+package aspace_xport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+var containersHeader = []string{"top_container_type", "top_container_indicator", "barcode", "location", "child_type", "child_indicator", "component_title", "component_level", "component_dates", "ref_id", "component_uri"}
+
+// the fields of an archival object used in the container list
+type containerComponent struct {
+	URI       string         `json:"uri"`
+	RefID     string         `json:"ref_id"`
+	Title     string         `json:"title"`
+	Level     string         `json:"level"`
+	Dates     []resolvedDate `json:"dates"`
+	Instances []struct {
+		SubContainer struct {
+			TopContainer map[string]string `json:"top_container"`
+			Type2        string            `json:"type_2"`
+			Indicator2   string            `json:"indicator_2"`
+		} `json:"sub_container"`
+	} `json:"instances"`
+}
+
+// the fields of a top container used in the container list, with its locations resolved
+type containerTopContainer struct {
+	Type               string `json:"type"`
+	Indicator          string `json:"indicator"`
+	Barcode            string `json:"barcode"`
+	ContainerLocations []struct {
+		Status   string `json:"status"`
+		Resolved struct {
+			Title string `json:"title"`
+		} `json:"_resolved"`
+	} `json:"container_locations"`
+}
+
+// the title of the top container's current location
+func (t containerTopContainer) location() string {
+	for _, location := range t.ContainerLocations {
+		if location.Status == "current" {
+			return location.Resolved.Title
+		}
+	}
+	return ""
+}
+
+func (c containerComponent) dates() string {
+	dates := []string{}
+	for _, d := range c.Dates {
+		if d.String() != "" {
+			dates = append(dates, d.String())
+		}
+	}
+	return strings.Join(dates, "; ")
+}
+
+// write a csv box list for a resource with a row for each container instance of each archival object in the tree,
+// in tree order
+func exportContainers(ctx context.Context, info ResourceInfo, res aspace.Resource, tree resourceTree, workerID int) FormatResult {
+	attempts := 0
+	failed := func(msg string, err error) FormatResult {
+		LogOnly(msg, ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	csvBytes := new(bytes.Buffer)
+	writer := csv.NewWriter(csvBytes)
+	err := writer.Write(containersHeader)
+	if err != nil {
+		return failed("could not write container list", err)
+	}
+
+	//top containers are shared by many components, get each once
+	topContainers := map[string]containerTopContainer{}
+	for _, record := range tree.records {
+		//stop fetching top containers if the export was cancelled, the worker abandons the resource
+		if ctx.Err() != nil {
+			return FormatResult{Status: "ERROR", Error: ctx.Err().Error(), Attempts: attempts}
+		}
+		aoBytes, ok := tree.archivalObjects[record.Ref]
+		if !ok {
+			continue
+		}
+
+		component := containerComponent{}
+		err := json.Unmarshal(aoBytes, &component)
+		if err != nil {
+			return failed(fmt.Sprintf("could not parse archival object %s", record.Ref), err)
+		}
+
+		for _, instance := range component.Instances {
+			tcURI := instance.SubContainer.TopContainer["ref"]
+			if tcURI == "" {
+				continue
+			}
+
+			topContainer, ok := topContainers[tcURI]
+			if !ok {
+				tcBytes, n, err := getRecordJSON(ctx, workerID, tcURI+"?resolve[]=container_locations")
+				attempts = max(attempts, n)
+				if err != nil {
+					return failed(fmt.Sprintf("could not retrieve top container %s", tcURI), err)
+				}
+				err = json.Unmarshal(tcBytes, &topContainer)
+				if err != nil {
+					return failed(fmt.Sprintf("could not parse top container %s", tcURI), err)
+				}
+				topContainers[tcURI] = topContainer
+			}
+
+			err = writer.Write([]string{
+				topContainer.Type,
+				topContainer.Indicator,
+				topContainer.Barcode,
+				topContainer.location(),
+				instance.SubContainer.Type2,
+				instance.SubContainer.Indicator2,
+				component.Title,
+				component.Level,
+				component.dates(),
+				component.RefID,
+				component.URI,
+			})
+			if err != nil {
+				return failed("could not write container list", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return failed("could not write container list", err)
+	}
+
+	//write the container list file
+	containersPath := filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.Directory(CONTAINERS), fmt.Sprintf("%s_containers.csv", resourceName(info, res)))
+	err = os.WriteFile(containersPath, csvBytes.Bytes(), 0777)
+	if err != nil {
+		return failed(fmt.Sprintf("could not write the container list %s", containersPath), err)
+	}
+
+	return FormatResult{Status: "SUCCESS", Path: containersPath, Error: "", Attempts: attempts}
+}
@@ -0,0 +1,113 @@
+package aspace_xport
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	oaiDCNamespace      = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	dcNamespace         = "http://purl.org/dc/elements/1.1/"
+	oaiDCSchemaLocation = "http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+)
+
+// an oai_dc record, the element names carry their prefixes since encoding/xml does not write namespace prefixes
+type dcRecord struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXsi       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Titles         []string `xml:"dc:title"`
+	Creators       []string `xml:"dc:creator"`
+	Contributors   []string `xml:"dc:contributor"`
+	Subjects       []string `xml:"dc:subject"`
+	Coverage       []string `xml:"dc:coverage"`
+	Descriptions   []string `xml:"dc:description"`
+	Publishers     []string `xml:"dc:publisher"`
+	Dates          []string `xml:"dc:date"`
+	Types          []string `xml:"dc:type"`
+	Formats        []string `xml:"dc:format"`
+	Identifiers    []string `xml:"dc:identifier"`
+	Languages      []string `xml:"dc:language"`
+	Rights         []string `xml:"dc:rights"`
+}
+
+// archivesspace note types written as dc descriptions, in order
+var dcDescriptionNotes = []string{"abstract", "scopecontent", "bioghist"}
+
+// archivesspace note types written as dc rights, in order
+var dcRightsNotes = []string{"accessrestrict", "userestrict"}
+
+// build a simple dublin core record from a resolved resource record
+func buildDC(r resolvedResource) ([]byte, error) {
+	dc := dcRecord{
+		XmlnsOAIDC:     oaiDCNamespace,
+		XmlnsDC:        dcNamespace,
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: oaiDCSchemaLocation,
+		Titles:         []string{r.Title},
+		Types:          []string{"Collection"},
+	}
+
+	for _, agent := range r.LinkedAgents {
+		switch agent.Role {
+		case "creator":
+			dc.Creators = append(dc.Creators, agent.Resolved.Title)
+		case "subject":
+			dc.Subjects = append(dc.Subjects, agent.Resolved.Title)
+		default:
+			dc.Contributors = append(dc.Contributors, agent.Resolved.Title)
+		}
+	}
+
+	for _, subject := range r.Subjects {
+		switch subject.termType() {
+		case "geographic", "temporal":
+			dc.Coverage = append(dc.Coverage, subject.Resolved.Title)
+		case "genre_form":
+			dc.Types = append(dc.Types, subject.Resolved.Title)
+		default:
+			dc.Subjects = append(dc.Subjects, subject.Resolved.Title)
+		}
+	}
+
+	for _, noteType := range dcDescriptionNotes {
+		dc.Descriptions = append(dc.Descriptions, r.noteText(noteType)...)
+	}
+
+	if r.Repository.Resolved.Name != "" {
+		dc.Publishers = append(dc.Publishers, r.Repository.Resolved.Name)
+	}
+
+	for _, date := range r.Dates {
+		if d := date.String(); d != "" {
+			dc.Dates = append(dc.Dates, d)
+		}
+	}
+
+	for _, extent := range r.Extents {
+		dc.Formats = append(dc.Formats, extent.String())
+	}
+
+	if id := r.identifier(); id != "" {
+		dc.Identifiers = append(dc.Identifiers, id)
+	}
+	if r.EADID != "" {
+		dc.Identifiers = append(dc.Identifiers, r.EADID)
+	}
+	dc.Identifiers = append(dc.Identifiers, r.URI)
+
+	dc.Languages = r.languages()
+
+	for _, noteType := range dcRightsNotes {
+		dc.Rights = append(dc.Rights, r.noteText(noteType)...)
+	}
+
+	dcBytes, err := xml.MarshalIndent(dc, "", "  ")
+	if err != nil {
+		return dcBytes, err
+	}
+
+	return []byte(xml.Header + strings.TrimSpace(string(dcBytes)) + "\n"), nil
+}
@@ -0,0 +1,165 @@
+package aspace_xport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// a resource record with its linked agents and subjects resolved, used to build descriptive metadata formats
+type resolvedResource struct {
+	Title         string              `json:"title"`
+	EADID         string              `json:"ead_id"`
+	URI           string              `json:"uri"`
+	ID0           string              `json:"id_0"`
+	ID1           string              `json:"id_1"`
+	ID2           string              `json:"id_2"`
+	ID3           string              `json:"id_3"`
+	Level         string              `json:"level"`
+	Dates         []resolvedDate      `json:"dates"`
+	Extents       []resolvedExtent    `json:"extents"`
+	LinkedAgents  []resolvedAgentLink `json:"linked_agents"`
+	Subjects      []resolvedSubject   `json:"subjects"`
+	Notes         []resolvedNote      `json:"notes"`
+	LangMaterials []struct {
+		LanguageAndScript struct {
+			Language string `json:"language"`
+		} `json:"language_and_script"`
+	} `json:"lang_materials"`
+	Repository struct {
+		Resolved struct {
+			Name string `json:"name"`
+		} `json:"_resolved"`
+	} `json:"repository"`
+}
+
+type resolvedDate struct {
+	DateType   string `json:"date_type"`
+	Label      string `json:"label"`
+	Expression string `json:"expression"`
+	Begin      string `json:"begin"`
+	End        string `json:"end"`
+}
+
+type resolvedExtent struct {
+	Number     string `json:"number"`
+	ExtentType string `json:"extent_type"`
+}
+
+type resolvedAgentLink struct {
+	Role     string `json:"role"`
+	Relator  string `json:"relator"`
+	Ref      string `json:"ref"`
+	Resolved struct {
+		Title         string `json:"title"`
+		JSONModelType string `json:"jsonmodel_type"`
+	} `json:"_resolved"`
+}
+
+type resolvedSubject struct {
+	Ref      string `json:"ref"`
+	Resolved struct {
+		Title string `json:"title"`
+		Terms []struct {
+			TermType string `json:"term_type"`
+		} `json:"terms"`
+	} `json:"_resolved"`
+}
+
+type resolvedNote struct {
+	JSONModelType string   `json:"jsonmodel_type"`
+	Type          string   `json:"type"`
+	Publish       bool     `json:"publish"`
+	Content       []string `json:"content"`
+	Subnotes      []struct {
+		Content string `json:"content"`
+		Publish bool   `json:"publish"`
+	} `json:"subnotes"`
+}
+
+// get the resource record with agents, subjects and the repository resolved
+func getResolvedResource(ctx context.Context, workerID int, info ResourceInfo) (resolvedResource, int, error) {
+	resource := resolvedResource{}
+	endpoint := fmt.Sprintf("%s?resolve[]=linked_agents&resolve[]=subjects&resolve[]=repository", info.URI())
+
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving resolved resource %s", info.URI()), func() error {
+		response, err := client.GetEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &resource)
+	})
+
+	return resource, attempts, err
+}
+
+func (r resolvedResource) identifier() string {
+	return MergeIDParts(r.ID0, r.ID1, r.ID2, r.ID3)
+}
+
+// a display string for a date, the expression if there is one otherwise the normalized begin and end
+func (d resolvedDate) String() string {
+	if d.Expression != "" {
+		return d.Expression
+	}
+	if d.End != "" && d.End != d.Begin {
+		return d.Begin + "/" + d.End
+	}
+	return d.Begin
+}
+
+func (e resolvedExtent) String() string {
+	return strings.TrimSpace(e.Number + " " + strings.ReplaceAll(e.ExtentType, "_", " "))
+}
+
+// the agent type without the `agent_` prefix: person, corporate_entity, family or software
+func (a resolvedAgentLink) agentType() string {
+	return strings.TrimPrefix(a.Resolved.JSONModelType, "agent_")
+}
+
+// the first term type of a subject, e.g. topical, geographic or genre_form
+func (s resolvedSubject) termType() string {
+	if len(s.Resolved.Terms) > 0 {
+		return s.Resolved.Terms[0].TermType
+	}
+	return "topical"
+}
+
+// the text of the notes of the given type, unpublished notes are only included with --include-unpublished-notes
+func (r resolvedResource) noteText(noteType string) []string {
+	texts := []string{}
+	for _, note := range r.Notes {
+		if note.Type != noteType || (!note.Publish && !exportOptions.UnpublishedNotes) {
+			continue
+		}
+		parts := append([]string{}, note.Content...)
+		for _, subnote := range note.Subnotes {
+			if subnote.Publish || exportOptions.UnpublishedNotes {
+				parts = append(parts, subnote.Content)
+			}
+		}
+		text := strings.TrimSpace(strings.Join(parts, " "))
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+func (r resolvedResource) languages() []string {
+	languages := []string{}
+	for _, lang := range r.LangMaterials {
+		if lang.LanguageAndScript.Language != "" {
+			languages = append(languages, lang.LanguageAndScript.Language)
+		}
+	}
+	return languages
+}
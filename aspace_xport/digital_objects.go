@@ -0,0 +1,142 @@
+package aspace_xport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+// the archivesspace digital object export endpoints, by digital object format
+var digitalObjectEndpoints = map[string]string{
+	"mets": "mets",
+	"mods": "mods",
+	"dc":   "dublin_core",
+}
+
+// the result of exporting a digital object linked to a resource in a single format
+type DigitalObjectResult struct {
+	URI      string `json:"uri"`
+	Format   string `json:"format"`
+	Status   string `json:"status"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+}
+
+// parse a comma separated list of digital object formats, duplicates are ignored
+func ParseDigitalObjectFormats(formats string) ([]string, error) {
+	doFormats := []string{}
+	if strings.TrimSpace(formats) == "" {
+		return doFormats, nil
+	}
+
+	for _, f := range strings.Split(formats, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := digitalObjectEndpoints[f]; !ok {
+			return doFormats, fmt.Errorf("unsupported digital object format %s, supported formats are `mets`, `mods` or `dc`", f)
+		}
+		if !contains(doFormats, f) {
+			doFormats = append(doFormats, f)
+		}
+	}
+	return doFormats, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// the uris of the digital objects linked from the instances of a resource
+func linkedDigitalObjectURIs(res aspace.Resource) []string {
+	uris := []string{}
+	for _, instance := range res.Instances {
+		ref := instance.DigitalObject["ref"]
+		if ref != "" && !contains(uris, ref) {
+			uris = append(uris, ref)
+		}
+	}
+	return uris
+}
+
+// export each digital object linked from the instances of a resource in each of the digital object formats, to a
+// [eadid]_digital_objects directory next to the resource's first export format, which no format writes to itself
+func exportDigitalObjects(ctx context.Context, info ResourceInfo, res aspace.Resource, workerID int) []DigitalObjectResult {
+	doResults := []DigitalObjectResult{}
+	doURIs := linkedDigitalObjectURIs(res)
+	if len(doURIs) == 0 {
+		return doResults
+	}
+
+	doDir := filepath.Join(outputDirectory(info, res, exportOptions.Formats[0]), resourceName(info, res)+"_digital_objects")
+	err := os.MkdirAll(doDir, 0755)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not create digital object directory %s", doDir), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+	}
+
+	for _, doURI := range doURIs {
+		for _, format := range exportOptions.DigitalObjects {
+			doResult := DigitalObjectResult{URI: doURI, Format: format}
+			if err != nil {
+				doResult.Status = "ERROR"
+				doResult.Error = err.Error()
+			} else {
+				doResult = exportDigitalObject(ctx, info, res, doResult, doDir, workerID)
+			}
+			doResults = append(doResults, doResult)
+		}
+	}
+
+	return doResults
+}
+
+func exportDigitalObject(ctx context.Context, info ResourceInfo, res aspace.Resource, doResult DigitalObjectResult, doDir string, workerID int) DigitalObjectResult {
+	//the digital object's id is the last segment of its uri, e.g. /repositories/2/digital_objects/12
+	doID := doResult.URI[strings.LastIndex(doResult.URI, "/")+1:]
+	endpoint := fmt.Sprintf("/repositories/%d/digital_objects/%s/%s.xml", info.RepoID, digitalObjectEndpoints[doResult.Format], doID)
+
+	//get the digital object record
+	var doBytes []byte
+	var err error
+	doResult.Attempts, err = withRetry(ctx, workerID, fmt.Sprintf("retrieving %s for %s", doResult.Format, doResult.URI), func() error {
+		response, err := client.GetEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		doBytes, err = io.ReadAll(response.Body)
+		return err
+	})
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not retrieve digital object %s", doResult.URI), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		doResult.Status = "ERROR"
+		doResult.Error = err.Error()
+		return doResult
+	}
+
+	//write the digital object file
+	doPath := filepath.Join(doDir, fmt.Sprintf("%s_%s.xml", doID, doResult.Format))
+	err = os.WriteFile(doPath, doBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the digital object record %s", doPath), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		doResult.Status = "ERROR"
+		doResult.Error = err.Error()
+		return doResult
+	}
+
+	doResult.Status = "SUCCESS"
+	doResult.Path = doPath
+	return doResult
+}
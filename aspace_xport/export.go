@@ -2,18 +2,22 @@ package aspace_xport
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nyudlts/go-aspace"
 )
 
 var (
-	numSkipped    = 0
 	reportFile    string
 	results       []ExportResult
 	startTime     time.Time
@@ -24,12 +28,23 @@ var (
 )
 
 type ExportOptions struct {
-	WorkDir              string
-	Format               ExportFormat
-	UnpublishedNotes     bool
-	UnpublishedResources bool
-	Workers              int
-	Reformat             bool
+	AppVersion           string         `json:"-"`
+	Environment          string         `json:"environment"`
+	WorkDir              string         `json:"work_dir"`
+	Formats              []ExportFormat `json:"formats"`
+	UnpublishedNotes     bool           `json:"include_unpublished_notes"`
+	UnpublishedResources bool           `json:"include_unpublished_resources"`
+	Target               string         `json:"target"`
+	Repository           int            `json:"repository"`
+	Resource             int            `json:"resource"`
+	ModifiedSince        time.Time      `json:"modified_since"`
+	Workers              int            `json:"workers"`
+	Reformat             bool           `json:"reformat"`
+	Validate             bool           `json:"validate"`
+	Retry                RetryPolicy    `json:"retry"`
+	Agents               bool           `json:"agents"`
+	DigitalObjects       []string       `json:"digital_objects"`
+	AccessionsCSV        bool           `json:"accessions_csv"`
 }
 
 type ExportFormat int
@@ -37,126 +52,616 @@ type ExportFormat int
 const (
 	EAD ExportFormat = iota
 	MARC
+	MODS
+	DC
+	EAD3
+	PDF
+	ASPACEJSON
+	CONTAINERS
 	UNSUPPORTED
 )
 
+func (f ExportFormat) String() string {
+	switch f {
+	case EAD:
+		return "ead"
+	case MARC:
+		return "marc"
+	case MODS:
+		return "mods"
+	case DC:
+		return "dc"
+	case EAD3:
+		return "ead3"
+	case PDF:
+		return "pdf"
+	case ASPACEJSON:
+		return "json"
+	case CONTAINERS:
+		return "containers"
+	default:
+		return "unsupported"
+	}
+}
+
+// the subdirectory of each repository directory that exports of the format are written to
+func (f ExportFormat) Directory() string {
+	switch f {
+	case MODS:
+		return "mods"
+	case DC:
+		return "dc"
+	case EAD3:
+		return "ead3"
+	case ASPACEJSON:
+		return "json"
+	case CONTAINERS:
+		return "containers"
+	default:
+		return "exports"
+	}
+}
+
+func (f ExportFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *ExportFormat) UnmarshalText(text []byte) error {
+	format, err := GetExportFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// the subdirectory of each repository directory that a format is written to, when more than one format is
+// exported each format is written to a directory named for the format
+func (o ExportOptions) Directory(f ExportFormat) string {
+	if len(o.Formats) > 1 {
+		return f.String()
+	}
+	return f.Directory()
+}
+
+// the subdirectory of each repository directory that invalid exports of a format are written to
+func (o ExportOptions) InvalidDirectory(f ExportFormat) string {
+	if len(o.Formats) > 1 {
+		return filepath.Join("invalid", f.String())
+	}
+	return "invalid"
+}
+
+// parse a comma separated list of export formats, duplicates are ignored
+func GetExportFormats(xportFormats string) ([]ExportFormat, error) {
+	formats := []ExportFormat{}
+	for _, f := range strings.Split(xportFormats, ",") {
+		format, err := GetExportFormat(strings.TrimSpace(f))
+		if err != nil {
+			return formats, err
+		}
+		if !hasFormat(formats, format) {
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+// the names of formats as a comma separated list
+func FormatNames(formats []ExportFormat) string {
+	names := []string{}
+	for _, format := range formats {
+		names = append(names, format.String())
+	}
+	return strings.Join(names, ",")
+}
+
+func hasFormat(formats []ExportFormat, format ExportFormat) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
 func GetExportFormat(xportFormat string) (ExportFormat, error) {
 	switch xportFormat {
 	case "ead":
 		return EAD, nil
 	case "marc":
 		return MARC, nil
+	case "mods":
+		return MODS, nil
+	case "dc":
+		return DC, nil
+	case "ead3":
+		return EAD3, nil
+	case "pdf":
+		return PDF, nil
+	case "json":
+		return ASPACEJSON, nil
+	case "containers":
+		return CONTAINERS, nil
 	default:
-		return UNSUPPORTED, fmt.Errorf("unsupported format error, %s, supported formats are `ead` or `marc`")
+		return UNSUPPORTED, fmt.Errorf("unsupported format error, %s, supported formats are `ead`, `ead3`, `marc`, `mods`, `dc`, `pdf`, `json` or `containers`", xportFormat)
+	}
+}
+
+type ResultSummary struct {
+	Successes int
+	Skipped   int
+	Warnings  int
+	Errors    int
+	Cancelled int
+}
+
+// summarize the results of the last call to ExportResources, including any agents exported
+func Summary() ResultSummary {
+	summary := ResultSummary{}
+	statuses := []string{}
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	for _, result := range agentResults {
+		statuses = append(statuses, result.Status)
+	}
+
+	for _, status := range statuses {
+		switch status {
+		case "SUCCESS":
+			summary.Successes = summary.Successes + 1
+		case "SKIPPED":
+			summary.Skipped = summary.Skipped + 1
+		case "WARNING":
+			summary.Warnings = summary.Warnings + 1
+		case "ERROR":
+			summary.Errors = summary.Errors + 1
+		case "CANCELLED":
+			summary.Cancelled = summary.Cancelled + 1
+		default:
+		}
+	}
+	return summary
+}
+
+// threshold at which a completed run is treated as failed, set with the --fail-on option
+type FailThreshold struct {
+	Disabled     bool    //never fail the run
+	OnWarning    bool    //fail if any resource has a warning
+	ErrorPercent float64 //fail if more than this percent of processed resources errored
+}
+
+// parse the --fail-on option: `none`, `error` (any error), `warning` (any warning or error) or a percentage of errors, e.g. `5%`
+func ParseFailOn(failOn string) (FailThreshold, error) {
+	failOn = strings.ToLower(strings.TrimSpace(failOn))
+	switch failOn {
+	case "none":
+		return FailThreshold{Disabled: true}, nil
+	case "error", "":
+		return FailThreshold{}, nil
+	case "warning":
+		return FailThreshold{OnWarning: true}, nil
+	}
+
+	if pct, ok := strings.CutSuffix(failOn, "%"); ok {
+		errorPercent, err := strconv.ParseFloat(pct, 64)
+		if err == nil && errorPercent >= 0 && errorPercent <= 100 {
+			return FailThreshold{ErrorPercent: errorPercent}, nil
+		}
+	}
+
+	return FailThreshold{}, fmt.Errorf("unsupported --fail-on value %s, use `none`, `error`, `warning` or a percentage of errors such as `5%%`", failOn)
+}
+
+// check a summary against the threshold, returns whether the run failed because of errors or because of warnings
+func (t FailThreshold) Check(summary ResultSummary) (bool, bool) {
+	if t.Disabled {
+		return false, false
+	}
+
+	processed := summary.Successes + summary.Skipped + summary.Warnings + summary.Errors
+	if summary.Errors > 0 && processed > 0 && float64(summary.Errors)*100/float64(processed) > t.ErrorPercent {
+		return true, false
 	}
+
+	if t.OnWarning && summary.Warnings > 0 {
+		return false, true
+	}
+
+	return false, false
 }
 
 type ExportResult struct {
-	Status string
-	URI    string
-	Error  string
+	Status     string         `json:"status"`
+	RepoID     int            `json:"repo_id"`
+	RepoSlug   string         `json:"repo_slug"`
+	ResourceID int            `json:"resource_id"`
+	URI        string         `json:"uri"`
+	EADID      string         `json:"eadid"`
+	Title      string         `json:"title"`
+	Publish    bool           `json:"publish"`
+	Identifier string         `json:"identifier,omitempty"` //the merged identifier of an accession
+	Date       string         `json:"date,omitempty"`       //the accession date of an accession
+	Size       int64          `json:"size"`                 //the total size of the files written for the resource
+	Error      string         `json:"error"`
+	Attempts   int            `json:"attempts"` //the highest number of attempts any ArchivesSpace request for the resource needed
+	Duration   time.Duration  `json:"duration"`
+	Formats    []FormatResult `json:"formats"`
+	Agents     []string       `json:"agents,omitempty"` //the uris of the linked agents, recorded when agents are exported
+
+	DigitalObjects []DigitalObjectResult `json:"digital_objects,omitempty"`
 }
 
-func ExportResources(options ExportOptions, stTime time.Time, fTime string, resInfo *[]ResourceInfo) error {
+// the result of exporting a resource in a single format
+type FormatResult struct {
+	Format   ExportFormat `json:"format"`
+	Status   string       `json:"status"`
+	Path     string       `json:"path"`
+	Size     int64        `json:"size"`
+	Checksum string       `json:"sha256"`
+	Error    string       `json:"error"`
+	Attempts int          `json:"attempts"`
+}
+
+// export the resources in resInfo, cancelling ctx stops the workers after their current resource and any
+// resources that were not processed are reported as CANCELLED
+func ExportResources(ctx context.Context, options ExportOptions, stTime time.Time, fTime string, resInfo *[]ResourceInfo) error {
 	exportOptions = options
 	startTime = stTime
 	formattedTime = fTime
 	resourceInfo = resInfo
-	resourceChunks := chunkResources()
-	resultChannel := make(chan []ExportResult)
 
-	for i, chunk := range resourceChunks {
-		go exportChunk(chunk, resultChannel, i+1)
+	if exportOptions.Workers < 1 {
+		exportOptions.Workers = 1
 	}
 
-	for range resourceChunks {
-		chunk := <-resultChannel
-		results = append(results, chunk...)
+	//open the journal so an interrupted run can be resumed
+	err := openJournal(exportOptions.WorkDir)
+	if err != nil {
+		return fmt.Errorf("could not open the export journal: %s", err.Error())
+	}
+	defer closeJournal()
+
+	//write the schemas to validate against to the work directory, they are removed when the export is done
+	if exportOptions.Validate == true {
+		err = writeSchemas(exportOptions.WorkDir)
+		if err != nil {
+			return fmt.Errorf("could not write the schemas to the work directory: %s", err.Error())
+		}
+		defer func() {
+			err := removeSchemas(exportOptions.WorkDir)
+			if err != nil {
+				LogOnly("could not remove the schemas from the work directory", WARNING, Fields{Error: err.Error()})
+			}
+		}()
 	}
 
-	err := CreateReport()
+	resourceChannel := make(chan ResourceInfo)
+	resultChannel := make(chan ExportResult)
+
+	//count the resources to be processed and start reporting progress
+	toProcess := 0
+	for _, rInfo := range *resourceInfo {
+		if !completed[rInfo.URI()] {
+			toProcess = toProcess + 1
+		}
+	}
+	prog := newProgress(toProcess, exportOptions.Target)
+	stopProgress := prog.run()
+
+	//start the workers, each pulls resources from the shared queue until it is closed
+	var wg sync.WaitGroup
+	for i := 1; i <= exportOptions.Workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			exportChunk(ctx, resourceChannel, resultChannel, workerID, prog)
+		}(i)
+	}
+
+	//feed the queue, skipping resources completed by a previous run
+	go func() {
+		for _, rInfo := range *resourceInfo {
+			if completed[rInfo.URI()] {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				close(resourceChannel)
+				return
+			case resourceChannel <- rInfo:
+			}
+		}
+		close(resourceChannel)
+	}()
+
+	//close the result channel once every worker has finished
+	go func() {
+		wg.Wait()
+		close(resultChannel)
+	}()
+
+	for result := range resultChannel {
+		results = append(results, result)
+		err = writeJournal(result)
+		if err != nil {
+			LogOnly("could not write result to the export journal", WARNING, Fields{Repository: result.RepoSlug, URI: result.URI, Error: err.Error()})
+		}
+	}
+	stopProgress()
+
+	//export the agents linked from the exported resources
+	if exportOptions.Agents == true && ctx.Err() == nil {
+		exportAgents(ctx)
+	}
+
+	//mark any resources that were not processed as cancelled
+	if ctx.Err() != nil {
+		processed := map[string]bool{}
+		for _, result := range results {
+			processed[result.URI] = true
+		}
+		cancelled := 0
+		for _, rInfo := range *resourceInfo {
+			if !processed[rInfo.URI()] {
+				results = append(results, ExportResult{Status: "CANCELLED", RepoID: rInfo.RepoID, RepoSlug: rInfo.RepoSlug, ResourceID: rInfo.ResourceID, URI: rInfo.URI(), Error: ""})
+				cancelled = cancelled + 1
+			}
+		}
+		PrintAndLog(fmt.Sprintf("export cancelled, %d resources were not processed", cancelled), WARNING)
+	}
+
+	err = CreateReport()
+	if err != nil {
+		return fmt.Errorf("could not create results report: %s", err.Error())
+	}
+
+	err = createJSONReport()
 	if err != nil {
-		fmt.Errorf("Could not create results report")
+		return fmt.Errorf("could not create json results report: %s", err.Error())
+	}
+
+	err = createManifest()
+	if err != nil {
+		return fmt.Errorf("could not create manifest: %s", err.Error())
+	}
+
+	if exportOptions.Target == AccessionsTarget && exportOptions.AccessionsCSV == true {
+		err = createAccessionsCSV()
+		if err != nil {
+			return fmt.Errorf("could not create accessions csv: %s", err.Error())
+		}
 	}
 
 	return nil
 }
 
-func chunkResources() [][]ResourceInfo {
-	var divided [][]ResourceInfo
-	ri := *resourceInfo
-	chunkSize := (len(ri) + exportOptions.Workers - 1) / exportOptions.Workers
+func exportChunk(ctx context.Context, resourceChannel <-chan ResourceInfo, resultChannel chan<- ExportResult, workerID int, prog *progress) {
+	PrintAndLog("starting worker", INFO, Fields{Worker: workerID})
+	processed := 0
+	var err error
+
+	//pull resources off the queue until it is empty or the export is cancelled
+	for rInfo := range resourceChannel {
+		if ctx.Err() != nil {
+			break
+		}
+
+		resourceStart := time.Now()
+		var result ExportResult
+		if rInfo.RecordType == AccessionsTarget {
+			result = exportAccession(ctx, rInfo, workerID)
+		} else {
+			result = exportResource(ctx, rInfo, workerID)
+		}
+		result.RepoID = rInfo.RepoID
+		result.RepoSlug = rInfo.RepoSlug
+		result.ResourceID = rInfo.ResourceID
+		result.Duration = time.Since(resourceStart)
+
+		//record the size and checksum of each file as written
+		for i, formatResult := range result.Formats {
+			if formatResult.Path == "" {
+				continue
+			}
+			result.Formats[i].Size, result.Formats[i].Checksum, err = fileChecksum(formatResult.Path)
+			if err != nil {
+				LogOnly(fmt.Sprintf("could not checksum %s", formatResult.Path), WARNING, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: result.URI, EADID: result.EADID, Error: err.Error()})
+			}
+			result.Size = result.Size + result.Formats[i].Size
+		}
+		for i, doResult := range result.DigitalObjects {
+			if doResult.Path == "" {
+				continue
+			}
+			result.DigitalObjects[i].Size, result.DigitalObjects[i].Checksum, err = fileChecksum(doResult.Path)
+			if err != nil {
+				LogOnly(fmt.Sprintf("could not checksum %s", doResult.Path), WARNING, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: result.URI, EADID: result.EADID, Error: err.Error()})
+			}
+			result.Size = result.Size + result.DigitalObjects[i].Size
+		}
 
-	for i := 0; i < len(*resourceInfo); i += chunkSize {
-		end := i + chunkSize
+		//a resource abandoned mid-retry is left out of the results to be reported as cancelled
+		if ctx.Err() != nil && result.Status == "ERROR" {
+			LogOnly("abandoned resource", WARNING, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: rInfo.URI()})
+			break
+		}
 
-		if end > len(*resourceInfo) {
-			end = len(*resourceInfo)
+		if result.Status == "SUCCESS" {
+			LogOnly("exported resource", INFO, Fields{Worker: workerID, Repository: result.RepoSlug, URI: result.URI, EADID: result.EADID, Duration: result.Duration})
 		}
 
-		divided = append(divided, ri[i:end])
+		prog.update(result.Status)
+		resultChannel <- result
+		processed = processed + 1
 	}
-	return divided
+
+	PrintAndLog(fmt.Sprintf("worker finished, processed %d resources", processed), INFO, Fields{Worker: workerID})
 }
 
-func exportChunk(resourceInfoChunk []ResourceInfo, resultChannel chan []ExportResult, workerID int) {
-	PrintAndLog(fmt.Sprintf("starting worker %d processing %d resources", workerID, len(resourceInfoChunk)), INFO)
-	var results = []ExportResult{}
+func exportResource(ctx context.Context, rInfo ResourceInfo, workerID int) ExportResult {
+	//get the resource object
+	uri := rInfo.URI()
+	var res aspace.Resource
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving resource %s", uri), func() error {
+		var err error
+		res, err = client.GetResource(rInfo.RepoID, rInfo.ResourceID)
+		return err
+	})
+	if err != nil {
+		PrintAndLog(fmt.Sprintf("could not retrieve resource after %d attempts", attempts), ERROR, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: uri, Error: err.Error()})
+		return ExportResult{Status: "ERROR", URI: uri, Error: err.Error(), Attempts: attempts}
+	}
 
-	//loop through the chunk
-	for i, rInfo := range resourceInfoChunk {
+	//check if the resource is set to be published
+	if exportOptions.UnpublishedResources == false && res.Publish != true {
+		LogOnly("resource not set to publish, skipping", INFO, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: res.URI, EADID: res.EADID})
+		return ExportResult{Status: "SKIPPED", URI: res.URI, EADID: res.EADID, Title: res.Title, Publish: res.Publish, Error: "", Attempts: attempts}
+	}
 
-		if i > 1 && (i-1)%50 == 0 {
-			PrintOnly(fmt.Sprintf("worker %d has completed %d exports", workerID, i-1), INFO)
+	//mods and dc are built from the resolved resource, which is retrieved once for both
+	var resolved resolvedResource
+	var resolvedErr error
+	if hasFormat(exportOptions.Formats, MODS) || hasFormat(exportOptions.Formats, DC) {
+		var resolvedAttempts int
+		resolved, resolvedAttempts, resolvedErr = getResolvedResource(ctx, workerID, rInfo)
+		attempts = max(attempts, resolvedAttempts)
+		if resolvedErr != nil {
+			LogOnly("could not retrieve resolved resource", ERROR, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: res.URI, EADID: res.EADID, Error: resolvedErr.Error()})
 		}
-		//get the resource object
-		res, err := client.GetResource(rInfo.RepoID, rInfo.ResourceID)
-		if err != nil {
-			results = append(results, ExportResult{Status: "ERROR", URI: "", Error: err.Error()})
-			PrintAndLog(fmt.Sprintf("worker %d could not retrieve resource %s", workerID, res.URI), ERROR)
-			continue
+	}
+
+	//json and containers both use every archival object in the resource's tree, which is retrieved once for both
+	var tree resourceTree
+	var treeErr error
+	if hasFormat(exportOptions.Formats, ASPACEJSON) || hasFormat(exportOptions.Formats, CONTAINERS) {
+		var treeAttempts int
+		tree, treeAttempts, treeErr = getResourceTreeRecords(ctx, workerID, rInfo)
+		attempts = max(attempts, treeAttempts)
+		if treeErr != nil && ctx.Err() == nil {
+			LogOnly("could not retrieve resource tree", ERROR, Fields{Worker: workerID, Repository: rInfo.RepoSlug, URI: res.URI, EADID: res.EADID, Error: treeErr.Error()})
 		}
+	}
 
-		//check if the resource is set to be published
-		if exportOptions.UnpublishedResources == false && res.Publish != true {
-			LogOnly(fmt.Sprintf("worker %d - resource %s not set to publish, skipping", workerID, res.URI), INFO)
-			numSkipped = numSkipped + 1
-			results = append(results, ExportResult{Status: "SKIPPED", URI: res.URI, Error: ""})
-			continue
+	result := ExportResult{URI: res.URI, EADID: res.EADID, Title: res.Title, Publish: res.Publish, Attempts: attempts}
+	if exportOptions.Agents == true {
+		result.Agents = linkedAgentURIs(res)
+	}
+	for _, format := range exportOptions.Formats {
+		var formatResult FormatResult
+		switch format {
+		case MARC:
+			formatResult = exportMarc(ctx, rInfo, res, workerID)
+		case EAD, EAD3:
+			formatResult = exportEAD(ctx, rInfo, res, format, workerID)
+		case MODS, DC:
+			if resolvedErr != nil {
+				formatResult = FormatResult{Status: "ERROR", Error: resolvedErr.Error()}
+			} else if format == MODS {
+				formatResult = exportMODS(rInfo, res, resolved, workerID)
+			} else {
+				formatResult = exportDC(rInfo, res, resolved, workerID)
+			}
+		case PDF:
+			formatResult = exportPDF(ctx, rInfo, res, workerID)
+		case ASPACEJSON, CONTAINERS:
+			if treeErr != nil {
+				formatResult = FormatResult{Status: "ERROR", Error: treeErr.Error()}
+			} else if format == ASPACEJSON {
+				formatResult = exportJSON(ctx, rInfo, res, tree, workerID)
+			} else {
+				formatResult = exportContainers(ctx, rInfo, res, tree, workerID)
+			}
+		default:
+			//there's an unsupported format, this shouldn't be possible
+			formatResult = FormatResult{Status: "ERROR", Error: "unsupported export format"}
 		}
+		formatResult.Format = format
+		result.Formats = append(result.Formats, formatResult)
+	}
+
+	//export the digital objects linked from the resource's instances
+	if len(exportOptions.DigitalObjects) > 0 {
+		result.DigitalObjects = exportDigitalObjects(ctx, rInfo, res, workerID)
+	}
+
+	return aggregateResult(result)
+}
 
-		if exportOptions.Format == MARC {
-			results = append(results, exportMarc(rInfo, res, workerID))
-		} else if exportOptions.Format == EAD {
-			results = append(results, exportEAD(rInfo, res, workerID))
+// set the status of a resource to the worst status of its formats and digital objects, and its error to their errors
+func aggregateResult(result ExportResult) ExportResult {
+	result.Status = "SUCCESS"
+	errs := []string{}
+	for _, formatResult := range result.Formats {
+		result.Attempts = max(result.Attempts, formatResult.Attempts)
+		switch formatResult.Status {
+		case "ERROR":
+			result.Status = "ERROR"
+		case "WARNING":
+			if result.Status != "ERROR" {
+				result.Status = "WARNING"
+			}
+		}
+		if formatResult.Error == "" {
+			continue
+		}
+		if len(result.Formats) > 1 {
+			errs = append(errs, fmt.Sprintf("%s: %s", formatResult.Format, formatResult.Error))
 		} else {
-			//there's an unsupported format, this shouldn't be possible
+			errs = append(errs, formatResult.Error)
 		}
 	}
+	for _, doResult := range result.DigitalObjects {
+		result.Attempts = max(result.Attempts, doResult.Attempts)
+		if doResult.Status == "ERROR" {
+			result.Status = "ERROR"
+			errs = append(errs, fmt.Sprintf("digital object %s %s: %s", doResult.URI, doResult.Format, doResult.Error))
+		}
+	}
+	result.Error = strings.Join(errs, "; ")
+	return result
+}
 
-	PrintAndLog(fmt.Sprintf("worker %d finished, processed %d resources", workerID, len(results)), INFO)
-	resultChannel <- results
+// the directory a resource's export in the format is written to, unpublished resources are written to the unpublished
+// directory for the formats that can not leave out unpublished content themselves
+func outputDirectory(info ResourceInfo, res aspace.Resource, format ExportFormat) string {
+	if exportOptions.UnpublishedResources == true && res.Publish == false {
+		switch format {
+		case MARC, MODS, DC:
+			return filepath.Join(exportOptions.WorkDir, info.RepoSlug, "unpublished")
+		}
+	}
+	return filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.Directory(format))
 }
 
-func exportMarc(info ResourceInfo, res aspace.Resource, workerID int) ExportResult {
+// the name used for a resource's own files and directories, its lowercased eadid or resource_[id] if it has none
+func resourceName(info ResourceInfo, res aspace.Resource) string {
+	if res.EADID == "" {
+		return fmt.Sprintf("resource_%d", info.ResourceID)
+	}
+	return strings.ToLower(res.EADID)
+}
+
+func exportMarc(ctx context.Context, info ResourceInfo, res aspace.Resource, workerID int) FormatResult {
 
 	//get the marc record
-	marcBytes, err := client.GetMARCAsByteArray(info.RepoID, info.ResourceID, exportOptions.UnpublishedNotes)
+	var marcBytes []byte
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving marc record for %s", res.URI), func() error {
+		var err error
+		marcBytes, err = client.GetMARCAsByteArray(info.RepoID, info.ResourceID, exportOptions.UnpublishedNotes)
+		return err
+	})
 	if err != nil {
-		LogOnly(fmt.Sprintf("worker %d - could not retrieve resource %s", workerID, res.URI), ERROR)
-		return ExportResult{Status: "ERROR", URI: res.URI, Error: err.Error()}
+		LogOnly("could not retrieve resource", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
 	}
 
 	//create the output filename
 	marcFilename := strings.ToLower(fmt.Sprintf("%s_%s.xml", res.EADID, formattedTime))
 
 	//set the location to write the marc record
-	var marcPath string
-	if exportOptions.UnpublishedResources == true && res.Publish == false {
-		marcPath = filepath.Join(exportOptions.WorkDir, info.RepoSlug, "unpublished", marcFilename)
-	} else {
-		marcPath = filepath.Join(exportOptions.WorkDir, info.RepoSlug, "exports", marcFilename)
-	}
+	marcPath := filepath.Join(outputDirectory(info, res, MARC), marcFilename)
 
 	//validate the output
 	warning := false
@@ -165,59 +670,170 @@ func exportMarc(info ResourceInfo, res aspace.Resource, workerID int) ExportResu
 	//write the marc file
 	err = os.WriteFile(marcPath, marcBytes, 0777)
 	if err != nil {
-		LogOnly(fmt.Sprintf("worker %d - could not write the marc record %s", workerID, res.URI), ERROR)
-		return ExportResult{Status: "ERROR", URI: "", Error: err.Error()}
+		LogOnly(fmt.Sprintf("could not write the marc record %s", marcPath), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
 	}
 
 	//return the result
 	if warning == true {
-		LogOnly(fmt.Sprintf("worker %d - exported resource %s - %s with warning", workerID, res.URI, marcFilename), WARNING)
-		return ExportResult{Status: "WARNING", URI: res.URI, Error: warningType}
+		LogOnly(fmt.Sprintf("exported resource to %s with warning", marcFilename), WARNING, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: warningType})
+		return FormatResult{Status: "WARNING", Path: marcPath, Error: warningType, Attempts: attempts}
+	}
+	return FormatResult{Status: "SUCCESS", Path: marcPath, Error: "", Attempts: attempts}
+}
+
+func exportMODS(info ResourceInfo, res aspace.Resource, resolved resolvedResource, workerID int) FormatResult {
+
+	//build the mods record from the resolved resource
+	modsBytes, err := buildMODS(resolved)
+	if err != nil {
+		LogOnly("could not create the mods record", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error()}
 	}
-	LogOnly(fmt.Sprintf("worker %d exported resource %s - %s", workerID, res.URI, res.EADID), INFO)
-	return ExportResult{Status: "SUCCESS", URI: res.URI, Error: ""}
+
+	//create the output filename
+	modsFilename := fmt.Sprintf("%s_mods.xml", resourceName(info, res))
+
+	//set the location to write the mods record
+	modsPath := filepath.Join(outputDirectory(info, res, MODS), modsFilename)
+
+	//write the mods file
+	err = os.WriteFile(modsPath, modsBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the mods record %s", modsPath), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error()}
+	}
+
+	return FormatResult{Status: "SUCCESS", Path: modsPath, Error: ""}
 }
 
-func exportEAD(info ResourceInfo, res aspace.Resource, workerID int) ExportResult {
+func exportDC(info ResourceInfo, res aspace.Resource, resolved resolvedResource, workerID int) FormatResult {
 
-	//get the ead as bytes
-	eadBytes, err := client.GetEADAsByteArray(info.RepoID, info.ResourceID, exportOptions.UnpublishedNotes)
+	//build the dc record from the resolved resource
+	dcBytes, err := buildDC(resolved)
 	if err != nil {
-		LogOnly(fmt.Sprintf("INFO worker %d could not retrieve resource %s", workerID, res.URI), ERROR)
-		return ExportResult{Status: "ERROR", URI: res.URI, Error: err.Error()}
+		LogOnly("could not create the dc record", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error()}
 	}
 
 	//create the output filename
+	dcFilename := fmt.Sprintf("%s_dc.xml", resourceName(info, res))
+
+	//set the location to write the dc record
+	dcPath := filepath.Join(outputDirectory(info, res, DC), dcFilename)
+
+	//write the dc file
+	err = os.WriteFile(dcPath, dcBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the dc record %s", dcPath), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error()}
+	}
+
+	return FormatResult{Status: "SUCCESS", Path: dcPath, Error: ""}
+}
+
+func exportEAD(ctx context.Context, info ResourceInfo, res aspace.Resource, format ExportFormat, workerID int) FormatResult {
+
+	//get the ead as bytes, ead3 is requested with the same options as ead 2002
+	ead3 := format == EAD3
+	var eadBytes []byte
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving %s for %s", format, res.URI), func() error {
+		var err error
+		if ead3 == true {
+			eadBytes, err = client.SerializeEAD(info.RepoID, info.ResourceID, true, exportOptions.UnpublishedNotes, false, true, false)
+		} else {
+			eadBytes, err = client.GetEADAsByteArray(info.RepoID, info.ResourceID, exportOptions.UnpublishedNotes)
+		}
+		return err
+	})
+	if err != nil {
+		LogOnly("could not retrieve resource", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	//create the output filename, ead 2002 keeps the eadid as it is
 	eadFilename := fmt.Sprintf("%s.xml", res.EADID)
-	outputFile := filepath.Join(exportOptions.WorkDir, info.RepoSlug, "exports", eadFilename)
+	if ead3 == true {
+		eadFilename = fmt.Sprintf("%s.xml", resourceName(info, res))
+	}
+	outputFile := filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.Directory(format), eadFilename)
 
 	//validate the output
 	warning := false
 	var warningType = ""
+	if exportOptions.Validate == true {
+		err = validateEAD(format, eadBytes)
+		if err != nil {
+			warning = true
+			warningType = fmt.Sprintf("failed validation: %s", err.Error())
+			outputFile = filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.InvalidDirectory(format), eadFilename)
+			LogOnly(fmt.Sprintf("failed validation, writing to %s", outputFile), WARNING, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		}
+	}
 
 	//create the output file
 	err = os.WriteFile(outputFile, eadBytes, 0777)
 	if err != nil {
-		LogOnly(fmt.Sprintf("worker %d - could not write the ead file %s", workerID, res.URI), ERROR)
-		return ExportResult{Status: "ERROR", URI: "", Error: err.Error()}
+		LogOnly(fmt.Sprintf("could not write the ead file %s", outputFile), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
 	}
 
 	//reformat the ead with tabs
 	if exportOptions.Reformat == true {
 		err = tabReformatXML(outputFile)
 		if err != nil {
-			LogOnly(fmt.Sprintf("worker %d - could not reformat %s", workerID, outputFile), WARNING)
+			LogOnly(fmt.Sprintf("could not reformat %s", outputFile), WARNING, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
 		}
 	}
 
 	//return the result
 
 	if warning == true {
-		LogOnly(fmt.Sprintf("worker %d exported resource %s - %s with warning", workerID, res.URI, eadFilename), WARNING)
-		return ExportResult{Status: "WARNING", URI: res.URI, Error: warningType}
+		LogOnly(fmt.Sprintf("exported resource to %s with warning", eadFilename), WARNING, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: warningType})
+		return FormatResult{Status: "WARNING", Path: outputFile, Error: warningType, Attempts: attempts}
 	}
-	LogOnly(fmt.Sprintf("worker %d exported resource %s - %s", workerID, res.URI, res.EADID), INFO)
-	return ExportResult{Status: "SUCCESS", URI: res.URI, Error: ""}
+	return FormatResult{Status: "SUCCESS", Path: outputFile, Error: "", Attempts: attempts}
+}
+
+func exportPDF(ctx context.Context, info ResourceInfo, res aspace.Resource, workerID int) FormatResult {
+
+	//get the pdf from the archivesspace print to pdf endpoint
+	endpoint := fmt.Sprintf("/repositories/%d/resource_descriptions/%d.pdf?include_unpublished=%t&include_daos=true&numbered_cs=false&print_pdf=true", info.RepoID, info.ResourceID, exportOptions.UnpublishedNotes)
+	var pdfBytes []byte
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving pdf for %s", res.URI), func() error {
+		response, err := client.GetEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		pdfBytes, err = io.ReadAll(response.Body)
+		return err
+	})
+	if err != nil {
+		LogOnly("could not retrieve resource", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	//archivesspace can return an error page instead of a pdf when generation fails
+	if !bytes.HasPrefix(pdfBytes, []byte("%PDF-")) {
+		err = fmt.Errorf("pdf generation failed, the response was not a pdf")
+		LogOnly("could not generate pdf", ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	//create the output filename
+	pdfFilename := fmt.Sprintf("%s.pdf", resourceName(info, res))
+	outputFile := filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.Directory(PDF), pdfFilename)
+
+	//create the output file
+	err = os.WriteFile(outputFile, pdfBytes, 0777)
+	if err != nil {
+		LogOnly(fmt.Sprintf("could not write the pdf file %s", outputFile), ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	return FormatResult{Status: "SUCCESS", Path: outputFile, Error: "", Attempts: attempts}
 }
 
 func tabReformatXML(path string) error {
@@ -244,8 +860,13 @@ func tabReformatXML(path string) error {
 }
 
 func MergeIDs(r aspace.Resource) string {
-	ids := r.ID0
-	for _, i := range []string{r.ID1, r.ID2, r.ID3} {
+	return MergeIDParts(r.ID0, r.ID1, r.ID2, r.ID3)
+}
+
+// join the four part identifier of a resource or accession with underscores, empty parts are left out
+func MergeIDParts(id0 string, id1 string, id2 string, id3 string) string {
+	ids := id0
+	for _, i := range []string{id1, id2, id3} {
 		if i != "" {
 			ids = ids + "_" + i
 		}
@@ -253,14 +874,35 @@ func MergeIDs(r aspace.Resource) string {
 	return ids
 }
 
+// a line in the text report for a result with a warning or error
+func reportLine(result ExportResult) string {
+	record := result.URI
+	if result.EADID != "" {
+		record = fmt.Sprintf("%s (%s)", result.URI, result.EADID)
+	}
+	return fmt.Sprintf("    %s %s: %s\n", result.RepoSlug, record, strings.ReplaceAll(result.Error, "\n", " "))
+}
+
 func CreateReport() error {
 	//seperate result types
 	successes := []ExportResult{}
 	errors := []ExportResult{}
 	warnings := []ExportResult{}
 	skipped := []ExportResult{}
+	cancelled := []ExportResult{}
+	invalid := 0
+	retried := 0
+	digitalObjects := 0
 
 	for _, result := range results {
+		for _, doResult := range result.DigitalObjects {
+			if doResult.Status == "SUCCESS" {
+				digitalObjects = digitalObjects + 1
+			}
+		}
+		if result.Attempts > 1 {
+			retried = retried + 1
+		}
 		switch result.Status {
 		case "SUCCESS":
 			successes = append(successes, result)
@@ -268,15 +910,20 @@ func CreateReport() error {
 			errors = append(errors, result)
 		case "WARNING":
 			warnings = append(warnings, result)
+			if strings.HasPrefix(result.Error, "failed validation") {
+				invalid = invalid + 1
+			}
 		case "SKIPPED":
 			skipped = append(skipped, result)
+		case "CANCELLED":
+			cancelled = append(cancelled, result)
 		default:
 		}
 	}
 
 	executionTime = time.Since(startTime)
 
-	reportFile = filepath.Join("aspace-export-report.txt")
+	reportFile = filepath.Join(exportOptions.WorkDir, "aspace-export-report.txt")
 	report, err := os.Create(reportFile)
 	if err != nil {
 		return err
@@ -287,26 +934,57 @@ func CreateReport() error {
 	fmt.Println()
 	msg := fmt.Sprintf("ASPACE-EXPORT REPORT\n====================\n")
 	msg = msg + fmt.Sprintf("Execution Time: %v", executionTime)
-	msg = msg + fmt.Sprintf("\n%d Resources proccessed:\n", len(results))
+	recordLabel := "Resources"
+	if exportOptions.Target == AccessionsTarget {
+		recordLabel = "Accessions"
+	}
+	msg = msg + fmt.Sprintf("\n%d %s proccessed:\n", len(results), recordLabel)
 	msg = msg + fmt.Sprintf("  %d Successful exports\n", len(successes))
-	msg = msg + fmt.Sprintf("  %d Skipped resources\n", len(skipped))
+	msg = msg + fmt.Sprintf("  %d Skipped %s\n", len(skipped), strings.ToLower(recordLabel))
+	msg = msg + fmt.Sprintf("  %d %s required retries\n", retried, recordLabel)
 	msg = msg + fmt.Sprintf("  %d Exports with warnings\n", len(warnings))
+	if len(exportOptions.DigitalObjects) > 0 {
+		msg = msg + fmt.Sprintf("  %d Digital object records exported\n", digitalObjects)
+	}
+	if exportOptions.Validate == true {
+		msg = msg + fmt.Sprintf("  %d Exports failed validation\n", invalid)
+	}
 
 	if len(warnings) > 0 {
 		for _, w := range warnings {
-			w.Error = strings.ReplaceAll(w.Error, "\n", " ")
-			msg = msg + fmt.Sprintf("    %v\n", w)
+			msg = msg + reportLine(w)
 		}
 	}
 
 	msg = msg + fmt.Sprintf("  %d Errors Encountered\n", len(errors))
 	if len(errors) > 0 {
 		for _, e := range errors {
-			e.Error = strings.ReplaceAll(e.Error, "\n", " ")
-			msg = msg + fmt.Sprintf("    %v\n", e)
+			msg = msg + reportLine(e)
 		}
 	}
 
+	if len(cancelled) > 0 {
+		msg = msg + fmt.Sprintf("  %d %s cancelled before export\n", len(cancelled), recordLabel)
+	}
+
+	if len(exportOptions.Formats) > 1 {
+		msg = msg + "\nFormats:\n"
+		for _, stats := range formatStats() {
+			msg = msg + fmt.Sprintf("  %s: %d successful, %d warnings, %d errors, %d bytes written\n",
+				stats.Format, stats.Successes, stats.Warnings, stats.Errors, stats.Bytes)
+		}
+	}
+
+	if len(agentResults) > 0 {
+		msg = msg + agentReport()
+	}
+
+	msg = msg + "\nRepositories:\n"
+	for _, stats := range repositoryStats() {
+		msg = msg + fmt.Sprintf("  %s (%d): %d successful, %d skipped, %d warnings, %d errors, %d bytes written, %v processing time\n",
+			stats.RepoSlug, stats.RepoID, stats.Successes, stats.Skipped, stats.Warnings, stats.Errors, stats.Bytes, stats.Elapsed.Round(time.Millisecond))
+	}
+
 	fmt.Println(msg)
 	_, err = writer.WriteString(msg)
 	if err != nil {
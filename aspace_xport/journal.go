@@ -0,0 +1,181 @@
+package aspace_xport
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	journalFilename        = "aspace-export-journal.jsonl"
+	journalOptionsFilename = "aspace-export-options.json"
+)
+
+var (
+	journal   *os.File
+	completed = map[string]bool{}
+)
+
+// open the journal in the work directory for appending, each completed ExportResult is written as a json line
+func openJournal(workDir string) error {
+	var err error
+	journal, err = os.OpenFile(filepath.Join(workDir, journalFilename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return err
+}
+
+func writeJournal(result ExportResult) error {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = journal.Write(append(resultBytes, '\n'))
+	return err
+}
+
+func closeJournal() error {
+	if journal == nil {
+		return nil
+	}
+	return journal.Close()
+}
+
+// load the journal of an interrupted run in workDir, resources that were exported or skipped are not exported again
+// and their results are carried into the report, returns the number of completed resources
+func LoadJournal(workDir string) (int, error) {
+	f, err := os.Open(filepath.Join(workDir, journalFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("no journal found in %s, can not resume", workDir)
+	} else if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	//later entries for a resource replace earlier ones, e.g. an error that succeeded on a previous resume
+	latest := map[string]ExportResult{}
+	order := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		result := ExportResult{}
+		err = json.Unmarshal(scanner.Bytes(), &result)
+		if err != nil {
+			//a partial last line is expected if the process was killed mid-write
+			LogOnly("skipping unreadable journal entry", WARNING, Fields{Error: err.Error()})
+			continue
+		}
+		if _, ok := latest[result.URI]; !ok {
+			order = append(order, result.URI)
+		}
+		latest[result.URI] = result
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	results = []ExportResult{}
+	completed = map[string]bool{}
+	for _, uri := range order {
+		result := latest[uri]
+		if result.Status == "ERROR" || result.Status == "CANCELLED" {
+			continue
+		}
+		results = append(results, result)
+		completed[uri] = true
+	}
+
+	return len(completed), nil
+}
+
+// the options that determine what a run writes, a resumed run must use the same options as the run it continues
+type journalOptions struct {
+	Environment          string `json:"environment"`
+	Target               string `json:"target"`
+	Repository           int    `json:"repository"`
+	Resource             int    `json:"resource"`
+	ModifiedSince        string `json:"modified_since"`
+	Formats              string `json:"formats"`
+	DigitalObjects       string `json:"digital_objects"`
+	Agents               bool   `json:"agents"`
+	UnpublishedNotes     bool   `json:"unpublished_notes"`
+	UnpublishedResources bool   `json:"unpublished_resources"`
+	Validate             bool   `json:"validate"`
+	Reformat             bool   `json:"reformat"`
+}
+
+func newJournalOptions(options ExportOptions) journalOptions {
+	//the time --modified-since resolved to, so `last-run` is compared by the time it meant for the original run
+	modifiedSince := ""
+	if !options.ModifiedSince.IsZero() {
+		modifiedSince = options.ModifiedSince.UTC().Format(time.RFC3339)
+	}
+
+	return journalOptions{
+		Environment:          options.Environment,
+		Target:               options.Target,
+		Repository:           options.Repository,
+		Resource:             options.Resource,
+		ModifiedSince:        modifiedSince,
+		Formats:              FormatNames(options.Formats),
+		DigitalObjects:       strings.Join(options.DigitalObjects, ","),
+		Agents:               options.Agents,
+		UnpublishedNotes:     options.UnpublishedNotes,
+		UnpublishedResources: options.UnpublishedResources,
+		Validate:             options.Validate,
+		Reformat:             options.Reformat,
+	}
+}
+
+// write the options of a new run to the work directory so a resume can check it continues with the same options
+func WriteJournalOptions(options ExportOptions) error {
+	optionsBytes, err := json.MarshalIndent(newJournalOptions(options), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(options.WorkDir, journalOptionsFilename), optionsBytes, 0644)
+}
+
+// check that a resumed run uses the same options as the run it continues, returns an error listing the differences
+func CheckJournalOptions(options ExportOptions) error {
+	optionsBytes, err := os.ReadFile(filepath.Join(options.WorkDir, journalOptionsFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no export options found in %s, can not resume", options.WorkDir)
+	} else if err != nil {
+		return err
+	}
+
+	original := journalOptions{}
+	err = json.Unmarshal(optionsBytes, &original)
+	if err != nil {
+		return fmt.Errorf("could not read the export options in %s: %s", options.WorkDir, err.Error())
+	}
+
+	resumed := newJournalOptions(options)
+	differences := []string{}
+	compare := func(option string, was interface{}, now interface{}) {
+		if was != now {
+			differences = append(differences, fmt.Sprintf("%s was `%v` and is `%v`", option, was, now))
+		}
+	}
+	compare("--environment", original.Environment, resumed.Environment)
+	compare("--target", original.Target, resumed.Target)
+	compare("--repository", original.Repository, resumed.Repository)
+	compare("--resource", original.Resource, resumed.Resource)
+	compare("--modified-since", original.ModifiedSince, resumed.ModifiedSince)
+	compare("--format", original.Formats, resumed.Formats)
+	compare("--digital-objects", original.DigitalObjects, resumed.DigitalObjects)
+	compare("--agents", original.Agents, resumed.Agents)
+	compare("--include-unpublished-notes", original.UnpublishedNotes, resumed.UnpublishedNotes)
+	compare("--include-unpublished-resources", original.UnpublishedResources, resumed.UnpublishedResources)
+	compare("--validate", original.Validate, resumed.Validate)
+	compare("--reformat", original.Reformat, resumed.Reformat)
+
+	if len(differences) > 0 {
+		return fmt.Errorf("a resumed run must use the same options as the original run: %s", strings.Join(differences, ", "))
+	}
+	return nil
+}
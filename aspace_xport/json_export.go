@@ -0,0 +1,81 @@
+package aspace_xport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+// write the resource record, its tree, the archival objects in the tree and the resource's top containers as the json
+// returned by archivesspace to a directory named for the resource's eadid
+func exportJSON(ctx context.Context, info ResourceInfo, res aspace.Resource, tree resourceTree, workerID int) FormatResult {
+	attempts := 0
+	failed := func(msg string, err error) FormatResult {
+		LogOnly(msg, ERROR, Fields{Worker: workerID, Repository: info.RepoSlug, URI: res.URI, EADID: res.EADID, Error: err.Error()})
+		return FormatResult{Status: "ERROR", Error: err.Error(), Attempts: attempts}
+	}
+
+	//create the resource directory
+	jsonDir := filepath.Join(exportOptions.WorkDir, info.RepoSlug, exportOptions.Directory(ASPACEJSON), resourceName(info, res))
+	for _, dir := range []string{jsonDir, filepath.Join(jsonDir, "archival_objects"), filepath.Join(jsonDir, "top_containers")} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return failed(fmt.Sprintf("could not create directory %s", dir), err)
+		}
+	}
+
+	//the resource record
+	resourceBytes, n, err := getRecordJSON(ctx, workerID, info.URI())
+	attempts = max(attempts, n)
+	if err != nil {
+		return failed("could not retrieve resource", err)
+	}
+	err = os.WriteFile(filepath.Join(jsonDir, "resource.json"), resourceBytes, 0777)
+	if err != nil {
+		return failed("could not write resource json", err)
+	}
+
+	//the tree, listing every record in order with its depth
+	err = os.WriteFile(filepath.Join(jsonDir, "tree.json"), tree.treeBytes, 0777)
+	if err != nil {
+		return failed("could not write resource tree json", err)
+	}
+
+	//each archival object in the tree, including its instances
+	for _, record := range tree.records {
+		aoBytes, ok := tree.archivalObjects[record.Ref]
+		if !ok {
+			continue
+		}
+		err = os.WriteFile(filepath.Join(jsonDir, "archival_objects", filepath.Base(record.Ref)+".json"), aoBytes, 0777)
+		if err != nil {
+			return failed(fmt.Sprintf("could not write archival object %s", record.Ref), err)
+		}
+	}
+
+	//the top containers of the resource's instances
+	tcURIs, n, err := getTopContainerURIs(ctx, workerID, info)
+	attempts = max(attempts, n)
+	if err != nil {
+		return failed("could not retrieve top containers", err)
+	}
+	for _, tcURI := range tcURIs {
+		if ctx.Err() != nil {
+			return FormatResult{Status: "ERROR", Error: ctx.Err().Error(), Attempts: attempts}
+		}
+		tcBytes, n, err := getRecordJSON(ctx, workerID, tcURI)
+		attempts = max(attempts, n)
+		if err != nil {
+			return failed(fmt.Sprintf("could not retrieve top container %s", tcURI), err)
+		}
+		err = os.WriteFile(filepath.Join(jsonDir, "top_containers", filepath.Base(tcURI)+".json"), tcBytes, 0777)
+		if err != nil {
+			return failed(fmt.Sprintf("could not write top container %s", tcURI), err)
+		}
+	}
+
+	return FormatResult{Status: "SUCCESS", Path: jsonDir, Error: "", Attempts: attempts}
+}
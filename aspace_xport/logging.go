@@ -1,9 +1,15 @@
 package aspace_xport
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
 type LogLevel int
@@ -16,12 +22,109 @@ const (
 	FATAL
 )
 
+type LogFormat int
+
+const (
+	TEXT LogFormat = iota
+	JSON
+)
+
+const logFilename = "aspace-export.log"
+
 var (
-	debug   = false
-	logfile = "aspace-export"
-	logger  *os.File
+	consoleLevel   = INFO
+	fileLevel      = INFO
+	quiet          = false
+	logfile        string
+	logger         *os.File
+	keepStartupLog bool
+	logFormat      = TEXT
+	logMutex       sync.Mutex
 )
 
+// structured fields attached to a log message
+type Fields struct {
+	Worker     int           `json:"worker,omitempty"`
+	Repository string        `json:"repository,omitempty"`
+	URI        string        `json:"uri,omitempty"`
+	EADID      string        `json:"eadid,omitempty"`
+	Duration   time.Duration `json:"-"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// render the fields as key=value pairs for text logs
+func (f Fields) String() string {
+	pairs := []string{}
+	if f.Worker != 0 {
+		pairs = append(pairs, fmt.Sprintf("worker=%d", f.Worker))
+	}
+	if f.Repository != "" {
+		pairs = append(pairs, fmt.Sprintf("repository=%s", f.Repository))
+	}
+	if f.URI != "" {
+		pairs = append(pairs, fmt.Sprintf("uri=%s", f.URI))
+	}
+	if f.EADID != "" {
+		pairs = append(pairs, fmt.Sprintf("eadid=%s", f.EADID))
+	}
+	if f.Duration != 0 {
+		pairs = append(pairs, fmt.Sprintf("duration=%v", f.Duration.Round(time.Millisecond)))
+	}
+	if f.Error != "" {
+		pairs = append(pairs, fmt.Sprintf("error=%q", strings.ReplaceAll(f.Error, "\n", " ")))
+	}
+	return strings.Join(pairs, " ")
+}
+
+type logEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"msg"`
+	Fields
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+func GetLogLevel(level string) (LogLevel, error) {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARNING":
+		return WARNING, nil
+	case "ERROR":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("unsupported log level %s, supported levels are DEBUG, INFO, WARNING or ERROR", level)
+	}
+}
+
+// set the minimum levels printed to the console and written to the log file, in quiet mode only
+// fatal messages and the final report are printed
+func SetLogLevels(console LogLevel, file LogLevel, q bool) {
+	consoleLevel = console
+	fileLevel = file
+	quiet = q
+}
+
+func printable(logLevel LogLevel) bool {
+	if quiet {
+		return logLevel == FATAL
+	}
+	return logLevel >= consoleLevel
+}
+
+func GetLogFormat(format string) (LogFormat, error) {
+	switch format {
+	case "text":
+		return TEXT, nil
+	case "json":
+		return JSON, nil
+	default:
+		return TEXT, fmt.Errorf("unsupported log format %s, supported formats are `text` or `json`", format)
+	}
+}
+
 func getLogLevelString(level LogLevel) string {
 	switch level {
 	case DEBUG:
@@ -39,20 +142,71 @@ func getLogLevelString(level LogLevel) string {
 	}
 }
 
-func CreateLogger(dbug bool) error {
+// create the startup log at logPath, or a uniquely named file in the temp directory if logPath is empty
+func CreateLogger(logPath string, format LogFormat) error {
+	logFormat = format
+	var err error
+	if logPath == "" {
+		logger, err = os.CreateTemp("", fmt.Sprintf("aspace-export-%d-*.log", os.Getpid()))
+	} else {
+		logger, err = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		keepStartupLog = true
+	}
+	if err != nil {
+		return err
+	}
+	logfile = logger.Name()
+
+	setLogOutput(logger)
+	PrintAndLog(fmt.Sprintf("logging to %s", logfile), INFO)
+	return nil
+}
+
+// reopen the logger in the work directory, copying what has been logged so far. the startup log is
+// removed unless it was set with the --log-file option
+func MoveLogger(workDir string) error {
+	newLoc := filepath.Join(workDir, logFilename)
+	if newLoc == logfile {
+		return nil
+	}
 
-	//create a log file
-	logfile = logfile + ".log"
+	//append so the log of a resumed run is kept
+	newLogger, err := os.OpenFile(newLoc, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
 
-	var err error
-	logger, err = os.Create(logfile)
+	startupLog, err := os.Open(logfile)
+	if err != nil {
+		newLogger.Close()
+		return err
+	}
+	defer startupLog.Close()
+
+	_, err = io.Copy(newLogger, startupLog)
+	if err != nil {
+		newLogger.Close()
+		return err
+	}
+
+	logMutex.Lock()
+	setLogOutput(newLogger)
+	err = logger.Close()
+	logger = newLogger
+	logMutex.Unlock()
 	if err != nil {
 		return err
 	}
 
-	log.SetOutput(logger)
+	if !keepStartupLog {
+		err = os.Remove(logfile)
+		if err != nil {
+			return err
+		}
+	}
+
+	logfile = newLoc
 	PrintAndLog(fmt.Sprintf("logging to %s", logfile), INFO)
-	debug = dbug
 	return nil
 }
 
@@ -64,31 +218,89 @@ func CloseLogger() error {
 	return nil
 }
 
-// logging and printing functions
-func PrintAndLog(msg string, logLevel LogLevel) {
-	if logLevel == DEBUG && debug == false {
-
+// in json mode the standard logger used by go-aspace is discarded so the log file only contains json lines
+func setLogOutput(w io.Writer) {
+	if logFormat == JSON {
+		log.SetOutput(io.Discard)
 	} else {
-		level := getLogLevelString(logLevel)
-		fmt.Printf("%s %s\n", level, msg)
-		log.Printf("%s %s", level, msg)
+		log.SetOutput(w)
 	}
 }
 
-func PrintOnly(msg string, logLevel LogLevel) {
-	if logLevel == DEBUG && debug == false {
+// combine the fields passed to the log helpers, non-empty values in later fields replace earlier ones
+func mergeFields(fields []Fields) Fields {
+	merged := Fields{}
+	for _, f := range fields {
+		if f.Worker != 0 {
+			merged.Worker = f.Worker
+		}
+		if f.Repository != "" {
+			merged.Repository = f.Repository
+		}
+		if f.URI != "" {
+			merged.URI = f.URI
+		}
+		if f.EADID != "" {
+			merged.EADID = f.EADID
+		}
+		if f.Duration != 0 {
+			merged.Duration = f.Duration
+		}
+		if f.Error != "" {
+			merged.Error = f.Error
+		}
+	}
+	return merged
+}
 
-	} else {
-		level := getLogLevelString(logLevel)
-		fmt.Printf("%s %s\n", level, msg)
+func writeLog(msg string, logLevel LogLevel, f Fields) {
+	if logFormat == JSON {
+		entry := logEntry{
+			Time:            time.Now(),
+			Level:           strings.Trim(getLogLevelString(logLevel), "[]"),
+			Message:         msg,
+			Fields:          f,
+			DurationSeconds: f.Duration.Seconds(),
+		}
+		entryBytes, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		logMutex.Lock()
+		defer logMutex.Unlock()
+		logger.Write(append(entryBytes, '\n'))
+		return
 	}
+	log.Printf("%s %s", getLogLevelString(logLevel), withFields(msg, f))
 }
 
-func LogOnly(msg string, logLevel LogLevel) {
-	if logLevel == DEBUG && debug == false {
+func withFields(msg string, f Fields) string {
+	if fields := f.String(); fields != "" {
+		return msg + " " + fields
+	}
+	return msg
+}
 
-	} else {
-		level := getLogLevelString(logLevel)
-		log.Printf("%s %s\n", level, msg)
+// logging and printing functions, an optional Fields adds structured context to the message
+func PrintAndLog(msg string, logLevel LogLevel, fields ...Fields) {
+	PrintOnly(msg, logLevel, fields...)
+	LogOnly(msg, logLevel, fields...)
+}
+
+func PrintOnly(msg string, logLevel LogLevel, fields ...Fields) {
+	if printable(logLevel) {
+		consoleMutex.Lock()
+		defer consoleMutex.Unlock()
+		//clear a live progress line, it is redrawn on the next update
+		if progressActive {
+			fmt.Print("\r\033[K")
+		}
+		fmt.Printf("%s %s\n", getLogLevelString(logLevel), withFields(msg, mergeFields(fields)))
+	}
+}
+
+func LogOnly(msg string, logLevel LogLevel, fields ...Fields) {
+	if logLevel >= fileLevel {
+		writeLog(msg, logLevel, mergeFields(fields))
 	}
 }
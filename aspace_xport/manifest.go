@@ -0,0 +1,148 @@
+package aspace_xport
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const manifestFilename = "manifest.csv"
+
+var manifestHeader = []string{"repo_slug", "resource_id", "uri", "eadid", "title", "publish", "format", "path", "size", "sha256", "status"}
+
+// get the size and sha256 checksum of a file, for a directory the size of every file in it and the checksum of a
+// sha256sum style listing of its files in path order
+func fileChecksum(path string) (int64, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, "", err
+	}
+	if info.IsDir() {
+		return dirChecksum(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func dirChecksum(dir string) (int64, string, error) {
+	var total int64
+	listing := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		size, checksum, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		total = total + size
+		fmt.Fprintf(listing, "%s  %s\n", checksum, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	return total, hex.EncodeToString(listing.Sum(nil)), nil
+}
+
+// write a csv manifest of every processed resource to the work directory, with a row for each format exported.
+// resources that were skipped or cancelled have a single row without a format, digital objects and exported agents follow the resources
+func createManifest() error {
+	manifest, err := os.Create(filepath.Join(exportOptions.WorkDir, manifestFilename))
+	if err != nil {
+		return err
+	}
+	defer manifest.Close()
+
+	writer := csv.NewWriter(manifest)
+	err = writer.Write(manifestHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		formatResults := result.Formats
+		if len(formatResults) == 0 {
+			formatResults = []FormatResult{{Status: result.Status}}
+		}
+
+		for _, formatResult := range formatResults {
+			format := ""
+			if formatResult.Path != "" || len(result.Formats) > 0 {
+				format = formatResult.Format.String()
+			}
+			err = writer.Write([]string{
+				result.RepoSlug,
+				strconv.Itoa(result.ResourceID),
+				result.URI,
+				result.EADID,
+				result.Title,
+				strconv.FormatBool(result.Publish),
+				format,
+				formatResult.Path,
+				strconv.FormatInt(formatResult.Size, 10),
+				formatResult.Checksum,
+				formatResult.Status,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	//digital objects are listed with the resource they are linked from, the format column is e.g. digital_object_mets
+	for _, result := range results {
+		for _, doResult := range result.DigitalObjects {
+			err = writer.Write([]string{
+				result.RepoSlug,
+				strconv.Itoa(result.ResourceID),
+				result.URI,
+				result.EADID,
+				result.Title,
+				strconv.FormatBool(result.Publish),
+				"digital_object_" + doResult.Format,
+				doResult.Path,
+				strconv.FormatInt(doResult.Size, 10),
+				doResult.Checksum,
+				doResult.Status,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	//agents have no repository, resource or title, the format column is eac-cpf
+	for _, agent := range agentResults {
+		err = writer.Write([]string{"", "", agent.URI, "", "", "", "eac-cpf", agent.Path, strconv.FormatInt(agent.Size, 10), agent.Checksum, agent.Status})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,212 @@
+package aspace_xport
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	modsNamespace      = "http://www.loc.gov/mods/v3"
+	modsSchemaLocation = "http://www.loc.gov/mods/v3 http://www.loc.gov/standards/mods/v3/mods-3-7.xsd"
+)
+
+type modsRecord struct {
+	XMLName             xml.Name           `xml:"mods"`
+	Xmlns               string             `xml:"xmlns,attr"`
+	XmlnsXsi            string             `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string             `xml:"xsi:schemaLocation,attr"`
+	Version             string             `xml:"version,attr"`
+	TitleInfo           modsTitleInfo      `xml:"titleInfo"`
+	Names               []modsName         `xml:"name"`
+	TypeOfResource      modsTypeOfResource `xml:"typeOfResource"`
+	OriginInfo          *modsOriginInfo    `xml:"originInfo,omitempty"`
+	Languages           []modsLanguage     `xml:"language"`
+	PhysicalDescription *modsPhysicalDesc  `xml:"physicalDescription,omitempty"`
+	Abstracts           []string           `xml:"abstract"`
+	Notes               []modsNote         `xml:"note"`
+	Subjects            []modsSubject      `xml:"subject"`
+	AccessConditions    []modsNote         `xml:"accessCondition"`
+	Identifiers         []modsNote         `xml:"identifier"`
+	RecordInfo          modsRecordInfo     `xml:"recordInfo"`
+}
+
+type modsTitleInfo struct {
+	Title string `xml:"title"`
+}
+
+type modsName struct {
+	Type     string    `xml:"type,attr,omitempty"`
+	NamePart string    `xml:"namePart"`
+	Role     *modsRole `xml:"role,omitempty"`
+}
+
+type modsRole struct {
+	RoleTerm string `xml:"roleTerm"`
+}
+
+type modsTypeOfResource struct {
+	Collection string `xml:"collection,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+type modsOriginInfo struct {
+	Dates []modsDate `xml:"dateCreated"`
+}
+
+type modsDate struct {
+	Encoding string `xml:"encoding,attr,omitempty"`
+	Point    string `xml:"point,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type modsLanguage struct {
+	LanguageTerm modsNote `xml:"languageTerm"`
+}
+
+type modsPhysicalDesc struct {
+	Extents []string `xml:"extent"`
+}
+
+type modsNote struct {
+	Type      string `xml:"type,attr,omitempty"`
+	Authority string `xml:"authority,attr,omitempty"`
+	Value     string `xml:",chardata"`
+}
+
+type modsSubject struct {
+	Topic      string    `xml:"topic,omitempty"`
+	Geographic string    `xml:"geographic,omitempty"`
+	Temporal   string    `xml:"temporal,omitempty"`
+	Genre      string    `xml:"genre,omitempty"`
+	Name       *modsName `xml:"name,omitempty"`
+}
+
+type modsRecordInfo struct {
+	RecordIdentifier string `xml:"recordIdentifier"`
+	RecordOrigin     string `xml:"recordOrigin"`
+}
+
+// agent types mapped to the mods name type attribute
+var modsNameTypes = map[string]string{
+	"person":           "personal",
+	"family":           "family",
+	"corporate_entity": "corporate",
+}
+
+// the mods note types and access conditions built from archivesspace note types
+var (
+	modsNoteTypes = map[string]string{
+		"bioghist":        "biographical/historical",
+		"scopecontent":    "content",
+		"arrangement":     "organization",
+		"custodhist":      "ownership",
+		"acqinfo":         "acquisition",
+		"prefercite":      "preferred citation",
+		"processinfo":     "action",
+		"relatedmaterial": "related material",
+	}
+	modsAccessConditionTypes = map[string]string{
+		"accessrestrict": "restriction on access",
+		"userestrict":    "use and reproduction",
+	}
+)
+
+// build a mods record from a resolved resource record
+func buildMODS(r resolvedResource) ([]byte, error) {
+	mods := modsRecord{
+		Xmlns:          modsNamespace,
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: modsSchemaLocation,
+		Version:        "3.7",
+		TitleInfo:      modsTitleInfo{Title: r.Title},
+		TypeOfResource: modsTypeOfResource{Value: "mixed material"},
+		RecordInfo:     modsRecordInfo{RecordIdentifier: r.URI, RecordOrigin: "Exported from ArchivesSpace by aspace-export"},
+	}
+
+	if r.Level == "collection" {
+		mods.TypeOfResource.Collection = "yes"
+	}
+
+	for _, agent := range r.LinkedAgents {
+		name := modsName{Type: modsNameTypes[agent.agentType()], NamePart: agent.Resolved.Title}
+		if agent.Role == "subject" {
+			mods.Subjects = append(mods.Subjects, modsSubject{Name: &name})
+			continue
+		}
+		name.Role = &modsRole{RoleTerm: agent.Role}
+		if agent.Relator != "" {
+			name.Role.RoleTerm = agent.Relator
+		}
+		mods.Names = append(mods.Names, name)
+	}
+
+	if len(r.Dates) > 0 {
+		origin := modsOriginInfo{}
+		for _, date := range r.Dates {
+			if date.Begin != "" && date.End != "" {
+				origin.Dates = append(origin.Dates,
+					modsDate{Encoding: "w3cdtf", Point: "start", Value: date.Begin},
+					modsDate{Encoding: "w3cdtf", Point: "end", Value: date.End})
+			}
+			if date.Expression != "" || date.Begin == "" || date.End == "" {
+				origin.Dates = append(origin.Dates, modsDate{Value: date.String()})
+			}
+		}
+		mods.OriginInfo = &origin
+	}
+
+	for _, language := range r.languages() {
+		mods.Languages = append(mods.Languages, modsLanguage{LanguageTerm: modsNote{Type: "code", Authority: "iso639-2b", Value: language}})
+	}
+
+	if len(r.Extents) > 0 {
+		physical := modsPhysicalDesc{}
+		for _, extent := range r.Extents {
+			physical.Extents = append(physical.Extents, extent.String())
+		}
+		mods.PhysicalDescription = &physical
+	}
+
+	mods.Abstracts = r.noteText("abstract")
+
+	for _, noteType := range sortedKeys(modsNoteTypes) {
+		for _, text := range r.noteText(noteType) {
+			mods.Notes = append(mods.Notes, modsNote{Type: modsNoteTypes[noteType], Value: text})
+		}
+	}
+
+	for _, subject := range r.Subjects {
+		s := modsSubject{}
+		switch subject.termType() {
+		case "geographic":
+			s.Geographic = subject.Resolved.Title
+		case "temporal":
+			s.Temporal = subject.Resolved.Title
+		case "genre_form":
+			s.Genre = subject.Resolved.Title
+		default:
+			s.Topic = subject.Resolved.Title
+		}
+		mods.Subjects = append(mods.Subjects, s)
+	}
+
+	for _, noteType := range sortedKeys(modsAccessConditionTypes) {
+		for _, text := range r.noteText(noteType) {
+			mods.AccessConditions = append(mods.AccessConditions, modsNote{Type: modsAccessConditionTypes[noteType], Value: text})
+		}
+	}
+
+	if id := r.identifier(); id != "" {
+		mods.Identifiers = append(mods.Identifiers, modsNote{Type: "local", Value: id})
+	}
+	if r.EADID != "" {
+		mods.Identifiers = append(mods.Identifiers, modsNote{Type: "eadid", Value: r.EADID})
+	}
+
+	modsBytes, err := xml.MarshalIndent(mods, "", "  ")
+	if err != nil {
+		return modsBytes, err
+	}
+
+	return []byte(xml.Header + strings.TrimSpace(string(modsBytes)) + "\n"), nil
+}
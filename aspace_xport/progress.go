@@ -0,0 +1,119 @@
+package aspace_xport
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ttyProgressInterval = 500 * time.Millisecond
+	logProgressInterval = 30 * time.Second
+)
+
+var (
+	consoleMutex   sync.Mutex
+	progressActive bool
+)
+
+// progress counters shared by all workers, label names what is being counted
+type progress struct {
+	total  int64
+	label  string
+	done   atomic.Int64
+	errors atomic.Int64
+	start  time.Time
+}
+
+func newProgress(total int, label string) *progress {
+	return &progress{total: int64(total), label: label, start: time.Now()}
+}
+
+func (p *progress) update(status string) {
+	p.done.Add(1)
+	if status == "ERROR" {
+		p.errors.Add(1)
+	}
+}
+
+func (p *progress) String() string {
+	done := p.done.Load()
+	elapsed := time.Since(p.start)
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+	}
+
+	eta := "--"
+	if rate > 0 && done < p.total {
+		eta = time.Duration(float64(p.total-done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	percent := 100.0
+	if p.total > 0 {
+		percent = float64(done) * 100 / float64(p.total)
+	}
+
+	return fmt.Sprintf("%d/%d (%.1f%%) %s, %d errors, %.1f/s, ETA %s", done, p.total, percent, p.label, p.errors.Load(), rate, eta)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// report progress until the returned function is called. on a terminal a single line is redrawn in place,
+// otherwise a summary line is printed periodically
+func (p *progress) run() func() {
+	tty := isTerminal(os.Stdout)
+	if quiet || (!tty && !printable(INFO)) {
+		return func() {}
+	}
+
+	interval := logProgressInterval
+	if tty {
+		interval = ttyProgressInterval
+		consoleMutex.Lock()
+		progressActive = true
+		consoleMutex.Unlock()
+	}
+
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if tty {
+					consoleMutex.Lock()
+					fmt.Printf("\r\033[K[PROGRESS] %s", p)
+					consoleMutex.Unlock()
+				} else {
+					PrintOnly(fmt.Sprintf("progress: %s", p), INFO)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-finished
+		if tty {
+			consoleMutex.Lock()
+			fmt.Printf("\r\033[K[PROGRESS] %s\n", p)
+			progressActive = false
+			consoleMutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package aspace_xport
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nyudlts/go-aspace"
+)
+
+const jsonReportFilename = "aspace-export-report.json"
+
+type JSONReport struct {
+	Metadata     ReportMetadata    `json:"metadata"`
+	Totals       map[string]int    `json:"totals"`
+	FormatTotals []FormatStats     `json:"format_totals"`
+	Repositories []RepositoryStats `json:"repositories"`
+	Resources    []JSONReportEntry `json:"resources"`
+	Agents       []AgentResult     `json:"agents,omitempty"`
+}
+
+// totals for a single repository, Elapsed is the time workers spent on the repository's resources
+type RepositoryStats struct {
+	RepoID         int           `json:"repo_id"`
+	RepoSlug       string        `json:"repo_slug"`
+	Successes      int           `json:"success"`
+	Skipped        int           `json:"skipped"`
+	Warnings       int           `json:"warning"`
+	Errors         int           `json:"error"`
+	Cancelled      int           `json:"cancelled"`
+	Bytes          int64         `json:"bytes_written"`
+	Elapsed        time.Duration `json:"-"`
+	ElapsedSeconds float64       `json:"elapsed_seconds"`
+}
+
+type ReportMetadata struct {
+	AppVersion      string        `json:"app_version"`
+	GoAspaceVersion string        `json:"go_aspace_version"`
+	Environment     string        `json:"environment"`
+	Options         ExportOptions `json:"options"`
+	StartTime       time.Time     `json:"start_time"`
+	EndTime         time.Time     `json:"end_time"`
+}
+
+type JSONReportEntry struct {
+	RepoID     int            `json:"repo_id"`
+	RepoSlug   string         `json:"repo_slug"`
+	ResourceID int            `json:"resource_id"`
+	URI        string         `json:"uri"`
+	EADID      string         `json:"eadid"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error"`
+	Attempts   int            `json:"attempts"`
+	Duration   float64        `json:"duration_seconds"`
+	Formats    []FormatResult `json:"formats"`
+
+	DigitalObjects []DigitalObjectResult `json:"digital_objects,omitempty"`
+}
+
+// aggregate the results by repository, sorted by slug
+func repositoryStats() []RepositoryStats {
+	statsMap := map[string]*RepositoryStats{}
+	for _, result := range results {
+		stats, ok := statsMap[result.RepoSlug]
+		if !ok {
+			stats = &RepositoryStats{RepoID: result.RepoID, RepoSlug: result.RepoSlug}
+			statsMap[result.RepoSlug] = stats
+		}
+
+		switch result.Status {
+		case "SUCCESS":
+			stats.Successes = stats.Successes + 1
+		case "SKIPPED":
+			stats.Skipped = stats.Skipped + 1
+		case "WARNING":
+			stats.Warnings = stats.Warnings + 1
+		case "ERROR":
+			stats.Errors = stats.Errors + 1
+		case "CANCELLED":
+			stats.Cancelled = stats.Cancelled + 1
+		default:
+		}
+
+		stats.Bytes = stats.Bytes + result.Size
+		stats.Elapsed = stats.Elapsed + result.Duration
+	}
+
+	repoStats := []RepositoryStats{}
+	for _, stats := range statsMap {
+		stats.ElapsedSeconds = stats.Elapsed.Seconds()
+		repoStats = append(repoStats, *stats)
+	}
+	sort.Slice(repoStats, func(i, j int) bool { return repoStats[i].RepoSlug < repoStats[j].RepoSlug })
+
+	return repoStats
+}
+
+// totals for a single format across all repositories
+type FormatStats struct {
+	Format    ExportFormat `json:"format"`
+	Successes int          `json:"success"`
+	Warnings  int          `json:"warning"`
+	Errors    int          `json:"error"`
+	Bytes     int64        `json:"bytes_written"`
+}
+
+// aggregate the format results by format, in the order the formats were requested
+func formatStats() []FormatStats {
+	stats := []FormatStats{}
+	for _, format := range exportOptions.Formats {
+		fStats := FormatStats{Format: format}
+		for _, result := range results {
+			for _, formatResult := range result.Formats {
+				if formatResult.Format != format {
+					continue
+				}
+				switch formatResult.Status {
+				case "SUCCESS":
+					fStats.Successes = fStats.Successes + 1
+				case "WARNING":
+					fStats.Warnings = fStats.Warnings + 1
+				case "ERROR":
+					fStats.Errors = fStats.Errors + 1
+				default:
+				}
+				fStats.Bytes = fStats.Bytes + formatResult.Size
+			}
+		}
+		stats = append(stats, fStats)
+	}
+	return stats
+}
+
+// write the machine-readable report to the work directory
+func createJSONReport() error {
+	report := JSONReport{
+		Metadata: ReportMetadata{
+			AppVersion:      exportOptions.AppVersion,
+			GoAspaceVersion: aspace.LibraryVersion,
+			Environment:     exportOptions.Environment,
+			Options:         exportOptions,
+			StartTime:       startTime,
+			EndTime:         time.Now(),
+		},
+		Totals:       map[string]int{},
+		FormatTotals: formatStats(),
+		Repositories: repositoryStats(),
+		Resources:    []JSONReportEntry{},
+	}
+
+	report.Agents = agentResults
+
+	for _, result := range results {
+		report.Totals[result.Status] = report.Totals[result.Status] + 1
+		report.Resources = append(report.Resources, JSONReportEntry{
+			RepoID:     result.RepoID,
+			RepoSlug:   result.RepoSlug,
+			ResourceID: result.ResourceID,
+			URI:        result.URI,
+			EADID:      result.EADID,
+			Status:     result.Status,
+			Error:      result.Error,
+			Attempts:   result.Attempts,
+			Duration:   result.Duration.Seconds(),
+			Formats:    result.Formats,
+
+			DigitalObjects: result.DigitalObjects,
+		})
+	}
+
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(exportOptions.WorkDir, jsonReportFilename), reportBytes, 0644)
+}
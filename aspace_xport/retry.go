@@ -0,0 +1,169 @@
+package aspace_xport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRetryDelay = 60 * time.Second
+
+var (
+	retryClasses   = []string{"5xx", "429", "connection"}
+	statusCodeExpr = regexp.MustCompile(`status-code: (\d{3})`)
+)
+
+type RetryPolicy struct {
+	MaxAttempts int             `json:"max_attempts"`
+	BaseDelay   time.Duration   `json:"base_delay"`
+	Jitter      float64         `json:"jitter"`
+	RetryOn     map[string]bool `json:"retry_on"`
+}
+
+// create a retry policy from the command line options, retryOn is a comma separated list of error classes
+func NewRetryPolicy(maxAttempts int, baseDelayMS int, jitter float64, retryOn string) (RetryPolicy, error) {
+	policy := RetryPolicy{RetryOn: map[string]bool{}}
+
+	if maxAttempts < 1 {
+		return policy, fmt.Errorf("max attempts must be at least 1, got %d", maxAttempts)
+	}
+	policy.MaxAttempts = maxAttempts
+
+	if baseDelayMS < 0 {
+		return policy, fmt.Errorf("retry delay can not be negative, got %d", baseDelayMS)
+	}
+	policy.BaseDelay = time.Duration(baseDelayMS) * time.Millisecond
+
+	if jitter < 0 || jitter > 1 {
+		return policy, fmt.Errorf("retry jitter must be between 0 and 1, got %v", jitter)
+	}
+	policy.Jitter = jitter
+
+	for _, class := range strings.Split(retryOn, ",") {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if class == "" {
+			continue
+		}
+		//timeouts are reported by go-aspace as failed connections
+		if class == "timeout" {
+			class = "connection"
+		}
+		if !isRetryClass(class) {
+			return policy, fmt.Errorf("unsupported retry error class %s, supported classes are %s", class, strings.Join(retryClasses, ", "))
+		}
+		policy.RetryOn[class] = true
+	}
+
+	return policy, nil
+}
+
+func isRetryClass(class string) bool {
+	for _, c := range retryClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// classify an error returned by go-aspace into one of the retry classes, returns an empty string if the error is not transient.
+// go-aspace does not return transport errors, it dereferences the nil response and callRecovered converts the panic to an
+// error, so timeouts can not be told apart from other failed connections and are both classed as connection
+func classifyError(err error) string {
+	msg := strings.ToLower(err.Error())
+
+	//archivesspace responded, the body may contain anything so only the status code is used
+	if match := statusCodeExpr.FindStringSubmatch(msg); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		switch {
+		case code == 429:
+			return "429"
+		case code >= 500:
+			return "5xx"
+		default:
+			return ""
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "connection"
+	}
+
+	for _, s := range []string{"no response", "timeout", "deadline exceeded", "connection reset", "connection refused", "broken pipe", "eof"} {
+		if strings.Contains(msg, s) {
+			return "connection"
+		}
+	}
+
+	return ""
+}
+
+func (p RetryPolicy) isRetryable(err error) bool {
+	class := classifyError(err)
+	return class != "" && p.RetryOn[class]
+}
+
+// exponential backoff for the given attempt with jitter applied
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	//double the delay for each attempt, stopping at the max so large attempt counts can not overflow
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay = delay * 2
+	}
+	if delay > maxRetryDelay || delay < 0 {
+		delay = maxRetryDelay
+	}
+	if p.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + p.Jitter*(rand.Float64()*2-1)))
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// call fn until it succeeds, returns a non-retryable error, the policy's max attempts are used up or ctx is cancelled
+// returns the number of attempts made
+func withRetry(ctx context.Context, workerID int, description string, fn func() error) (int, error) {
+	policy := exportOptions.Retry
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = callRecovered(fn)
+		if err == nil {
+			return attempt, nil
+		}
+
+		if attempt >= policy.MaxAttempts || !policy.isRetryable(err) {
+			return attempt, err
+		}
+
+		delay := policy.backoff(attempt)
+		LogOnly(fmt.Sprintf("attempt %d of %d %s failed, retrying in %v", attempt, policy.MaxAttempts, description, delay), WARNING, Fields{Worker: workerID, Error: err.Error()})
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// go-aspace dereferences a nil response when the http transport fails, convert the panic to an error
+func callRecovered(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("no response from ArchivesSpace: %v", r)
+		}
+	}()
+	return fn()
+}
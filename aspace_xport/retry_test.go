@@ -0,0 +1,54 @@
+package aspace_xport
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	//a go-aspace transport failure, do() reads the status code of the nil response
+	nilResponse := callRecovered(func() error {
+		var response *http.Response
+		if response.StatusCode != 200 {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"404", errors.New("ArchivesSpace responded with a non-200:\nstatus-code: 404\n{\"error\":\"Resource not found\"}"), ""},
+		{"412", errors.New("ArchivesSpace responded with a non-200:\nstatus-code: 412\n{\"code\":\"SESSION_GONE\",\"error\":\"No session found for timeout\"}"), ""},
+		{"429", errors.New("ArchivesSpace responded with a non-200:\nstatus-code: 429\nToo Many Requests"), "429"},
+		{"500", errors.New("ArchivesSpace responded with a non-200:\nstatus-code: 500\n{\"error\":\"Sequel::PoolTimeout\"}"), "5xx"},
+		{"503", errors.New("ArchivesSpace responded with a non-200:\nstatus-code: 503\nService Unavailable"), "5xx"},
+		{"nil response", nilResponse, "connection"},
+		{"parse error", errors.New("invalid character '<' looking for beginning of value"), ""},
+	}
+
+	for _, test := range tests {
+		if got := classifyError(test.err); got != test.want {
+			t.Errorf("%s: classifyError(%q) = %q, want %q", test.name, test.err.Error(), got, test.want)
+		}
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, Jitter: 1}
+	for _, attempt := range []int{1, 7, 35, 63, 64, 1000} {
+		delay := policy.backoff(attempt)
+		if delay < 0 || delay > maxRetryDelay {
+			t.Errorf("backoff(%d) = %v, want between 0 and %v", attempt, delay, maxRetryDelay)
+		}
+	}
+
+	policy.Jitter = 0
+	if delay := policy.backoff(1000); delay != maxRetryDelay {
+		t.Errorf("backoff(1000) = %v, want %v", delay, maxRetryDelay)
+	}
+}
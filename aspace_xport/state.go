@@ -0,0 +1,94 @@
+package aspace_xport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const stateFilename = "aspace-export-state.json"
+
+// the state file persists the start time of the last successful run for each environment, repository and format
+type ExportState struct {
+	LastRun map[string]time.Time `json:"last_run"`
+}
+
+// the formats of a multi-format run are sorted so the same formats given in another order share a key
+func stateKey(environment string, repository int, format string) string {
+	formats := strings.Split(format, ",")
+	sort.Strings(formats)
+	return fmt.Sprintf("%s:%d:%s", environment, repository, strings.Join(formats, ","))
+}
+
+// load the state file from the export location, a missing file returns an empty state
+func LoadState(exportLocation string) (ExportState, error) {
+	state := ExportState{LastRun: map[string]time.Time{}}
+
+	stateBytes, err := os.ReadFile(filepath.Join(exportLocation, stateFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(stateBytes, &state)
+	if err != nil {
+		return state, fmt.Errorf("could not parse state file %s: %s", stateFilename, err.Error())
+	}
+
+	if state.LastRun == nil {
+		state.LastRun = map[string]time.Time{}
+	}
+
+	return state, nil
+}
+
+// record the start time of a successful run and write the state file to the export location
+func SaveState(exportLocation string, state ExportState, environment string, repository int, format string, runTime time.Time) error {
+	state.LastRun[stateKey(environment, repository, format)] = runTime
+
+	stateBytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(exportLocation, stateFilename)
+	err = os.WriteFile(statePath, stateBytes, 0644)
+	if err != nil {
+		return err
+	}
+
+	PrintAndLog(fmt.Sprintf("recorded run time %s in %s", runTime.Format(time.RFC3339), statePath), INFO)
+	return nil
+}
+
+// parse the --modified-since option, either a timestamp or `last-run`, a zero time means export everything
+func GetModifiedSince(modifiedSince string, state ExportState, environment string, repository int, format string) (time.Time, error) {
+	modifiedSince = strings.TrimSpace(modifiedSince)
+	if modifiedSince == "" {
+		return time.Time{}, nil
+	}
+
+	if modifiedSince == "last-run" {
+		lastRun, ok := state.LastRun[stateKey(environment, repository, format)]
+		if !ok {
+			PrintAndLog("no previous run recorded in the state file, exporting all resources", WARNING)
+			return time.Time{}, nil
+		}
+		return lastRun, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		t, err := time.ParseInLocation(layout, modifiedSince, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("could not parse --modified-since value %s, use `last-run`, YYYY-MM-DD or an RFC3339 timestamp", modifiedSince)
+}
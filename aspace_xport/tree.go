@@ -0,0 +1,107 @@
+package aspace_xport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// a record in a resource's tree as listed by the ordered_records endpoint, the resource itself is at depth 0
+type treeRecord struct {
+	Ref   string `json:"ref"`
+	Level string `json:"level"`
+	Depth int    `json:"depth"`
+}
+
+// get the raw json of an archivesspace record
+func getRecordJSON(ctx context.Context, workerID int, uri string) ([]byte, int, error) {
+	var recordBytes []byte
+	attempts, err := withRetry(ctx, workerID, fmt.Sprintf("retrieving %s", uri), func() error {
+		response, err := client.GetEndpoint(uri)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		recordBytes, err = io.ReadAll(response.Body)
+		return err
+	})
+	return recordBytes, attempts, err
+}
+
+// get every record in a resource's tree in tree order, along with the raw json of the listing
+func getResourceTree(ctx context.Context, workerID int, info ResourceInfo) ([]treeRecord, []byte, int, error) {
+	ordered := struct {
+		URIs []treeRecord `json:"uris"`
+	}{}
+
+	treeBytes, attempts, err := getRecordJSON(ctx, workerID, fmt.Sprintf("%s/ordered_records", info.URI()))
+	if err != nil {
+		return ordered.URIs, treeBytes, attempts, err
+	}
+
+	err = json.Unmarshal(treeBytes, &ordered)
+	return ordered.URIs, treeBytes, attempts, err
+}
+
+// get the uris of the top containers of every instance in a resource's tree
+func getTopContainerURIs(ctx context.Context, workerID int, info ResourceInfo) ([]string, int, error) {
+	uris := []string{}
+	refs := []map[string]string{}
+
+	tcBytes, attempts, err := getRecordJSON(ctx, workerID, fmt.Sprintf("%s/top_containers", info.URI()))
+	if err != nil {
+		return uris, attempts, err
+	}
+
+	err = json.Unmarshal(tcBytes, &refs)
+	if err != nil {
+		return uris, attempts, err
+	}
+
+	for _, ref := range refs {
+		if ref["ref"] != "" {
+			uris = append(uris, ref["ref"])
+		}
+	}
+	return uris, attempts, nil
+}
+
+// a resource's tree and the json of each archival object in it, retrieved once for the json and containers formats
+type resourceTree struct {
+	records         []treeRecord
+	treeBytes       []byte
+	archivalObjects map[string][]byte
+}
+
+// get a resource's tree and every archival object in it, stops with ctx's error if the export is cancelled
+func getResourceTreeRecords(ctx context.Context, workerID int, info ResourceInfo) (resourceTree, int, error) {
+	tree := resourceTree{archivalObjects: map[string][]byte{}}
+
+	var err error
+	var attempts int
+	tree.records, tree.treeBytes, attempts, err = getResourceTree(ctx, workerID, info)
+	if err != nil {
+		return tree, attempts, fmt.Errorf("could not retrieve resource tree: %s", err.Error())
+	}
+
+	for _, record := range tree.records {
+		if ctx.Err() != nil {
+			return tree, attempts, ctx.Err()
+		}
+		if !strings.Contains(record.Ref, "/archival_objects/") {
+			continue
+		}
+
+		aoBytes, n, err := getRecordJSON(ctx, workerID, record.Ref)
+		attempts = max(attempts, n)
+		if err != nil {
+			return tree, attempts, fmt.Errorf("could not retrieve archival object %s: %s", record.Ref, err.Error())
+		}
+		tree.archivalObjects[record.Ref] = aoBytes
+	}
+
+	return tree, attempts, nil
+}
@@ -1,19 +1,32 @@
 package aspace_xport
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/nyudlts/go-aspace"
 )
 
+// a record to export, RecordType is empty for resources or AccessionsTarget for accessions
 type ResourceInfo struct {
 	RepoID     int
 	RepoSlug   string
 	ResourceID int
+	RecordType string
+}
+
+func (r ResourceInfo) URI() string {
+	recordType := r.RecordType
+	if recordType == "" {
+		recordType = ResourcesTarget
+	}
+	return fmt.Sprintf("/repositories/%d/%s/%d", r.RepoID, recordType, r.ResourceID)
 }
 
 var client *aspace.ASClient
@@ -27,8 +40,18 @@ func CreateAspaceClient(config string, environment string, timeout int) error {
 	return nil
 }
 
+// the keys of a string map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // check the application flags
-func CheckFlags(config string, environment string, format string, resource int, repository int) error {
+func CheckFlags(config string, environment string, format string, resource int, repository int, target string, agents bool, digitalObjects string, unpublishedResources bool) error {
 	//check if the config file is set
 	if config == "" {
 		return fmt.Errorf("location of go-aspace config file is mandatory, set the --config option when running aspace-export")
@@ -43,9 +66,33 @@ func CheckFlags(config string, environment string, format string, resource int,
 		return fmt.Errorf("environment to run export against is mandatory, set the --env option when running aspace=export")
 	}
 
-	//check that the format is either `ead` or `marc`
-	if format != "marc" && format != "ead" {
-		return fmt.Errorf("format must be either `ead` or `marc`, set the --format option when running aspace-export")
+	//check that the target is supported
+	if err := CheckTarget(target); err != nil {
+		return fmt.Errorf("%s, set the --target option when running aspace-export", err.Error())
+	}
+
+	//check that the formats are supported, accessions are only exported as json
+	formats, err := GetExportFormats(format)
+	if err != nil {
+		return fmt.Errorf("%s, set the --format option when running aspace-export", err.Error())
+	}
+	if target == AccessionsTarget {
+		if len(formats) != 1 || formats[0] != ASPACEJSON {
+			return fmt.Errorf("accessions can only be exported as `json`, set the --format option when running aspace-export")
+		}
+		if resource != 0 {
+			return fmt.Errorf("the --resource option can not be used with the accessions target")
+		}
+		//these options only apply to resources
+		if agents == true {
+			return fmt.Errorf("the --agents option can not be used with the accessions target")
+		}
+		if digitalObjects != "" {
+			return fmt.Errorf("the --digital-objects option can not be used with the accessions target")
+		}
+		if unpublishedResources == true {
+			return fmt.Errorf("the --include-unpublished-resources option can not be used with the accessions target")
+		}
 	}
 
 	//check that a repository id is set if a resource id is set
@@ -69,6 +116,35 @@ func CheckPath(path string) error {
 	return nil
 }
 
+// check that the export location is an existing, writable directory with at least minFreeMB of free space
+func CheckExportLocation(path string, minFreeMB int) error {
+	err := CheckPath(path)
+	if err != nil {
+		return err
+	}
+
+	//check the location is writable by creating and removing a file
+	f, err := os.CreateTemp(path, ".aspace-export-")
+	if err != nil {
+		return fmt.Errorf("export location %s is not writable: %s", path, err.Error())
+	}
+	f.Close()
+	err = os.Remove(f.Name())
+	if err != nil {
+		return err
+	}
+
+	free, err := freeSpace(path)
+	if err != nil {
+		return fmt.Errorf("could not determine free space at %s: %s", path, err.Error())
+	}
+	if free < uint64(minFreeMB)*1024*1024 {
+		return fmt.Errorf("export location %s has %d MB free, at least %d MB is required", path, free/1024/1024, minFreeMB)
+	}
+
+	return nil
+}
+
 // get a map of repository slugs and an id --TO DO reverse map order -- index by ID
 func GetRepositoryMap(repository int, environment string) (map[string]int, error) {
 	repositories := make(map[string]int)
@@ -97,8 +173,8 @@ func GetRepositoryMap(repository int, environment string) (map[string]int, error
 	return repositories, nil
 }
 
-// get a slice of ResourceInfo objects for a repository
-func GetResourceIDs(repMap map[string]int, resource int) ([]ResourceInfo, error) {
+// get a slice of ResourceInfo objects for a repository, a non-zero modifiedSince only returns resources modified after that time
+func GetResourceIDs(repMap map[string]int, resource int, modifiedSince time.Time) ([]ResourceInfo, error) {
 
 	resources := []ResourceInfo{}
 
@@ -112,7 +188,13 @@ func GetResourceIDs(repMap map[string]int, resource int) ([]ResourceInfo, error)
 			continue
 		}
 
-		resourceIDs, err := client.GetResourceIDs(repositoryID)
+		var resourceIDs []int
+		var err error
+		if modifiedSince.IsZero() {
+			resourceIDs, err = client.GetResourceIDs(repositoryID)
+		} else {
+			resourceIDs, err = getModifiedIDs(repositoryID, ResourcesTarget, modifiedSince)
+		}
 		if err != nil {
 			return resources, err
 		}
@@ -129,11 +211,35 @@ func GetResourceIDs(repMap map[string]int, resource int) ([]ResourceInfo, error)
 	return resources, nil
 }
 
+// get the ids of the resources or accessions in a repository whose system_mtime is after modifiedSince
+func getModifiedIDs(repositoryID int, recordType string, modifiedSince time.Time) ([]int, error) {
+	resourceIDs := []int{}
+	endpoint := fmt.Sprintf("/repositories/%d/%s?all_ids=true&modified_since=%d", repositoryID, recordType, modifiedSince.Unix())
+
+	response, err := client.GetEndpoint(endpoint)
+	if err != nil {
+		return resourceIDs, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return resourceIDs, err
+	}
+
+	err = json.Unmarshal(body, &resourceIDs)
+	if err != nil {
+		return resourceIDs, err
+	}
+
+	return resourceIDs, nil
+}
+
 // check that a work directory does not exist if so create it
 func CreateWorkDirectory(workDirPath string) error {
 	//determine if the directory already exists or if there is an error, if so return an error
 	if _, err := os.Stat(workDirPath); err == nil {
-		return fmt.Errorf("work directory %s already exists")
+		return fmt.Errorf("work directory %s already exists", workDirPath)
 	} else if errors.Is(err, os.ErrNotExist) {
 		//the workDir doesn't exist -- create it if there are no other errors
 	} else {
@@ -149,34 +255,75 @@ func CreateWorkDirectory(workDirPath string) error {
 	return nil
 }
 
-// create the repository, export, failure and unpublished sub directories in the work directory
-func CreateExportDirectories(workDirPath string, repositoryMap map[string]int, unpublishedResources bool) error {
+// create the agents directory and the repository, export, failure, unpublished and invalid sub directories in the
+// work directory
+func CreateExportDirectories(workDirPath string, repositoryMap map[string]int, options ExportOptions) error {
+	unpublishedResources := options.UnpublishedResources
+	validate := options.Validate
+
+	//agents are not specific to a repository and are written to the work directory
+	if options.Agents == true {
+		agentsDir := filepath.Join(workDirPath, agentsDirectory)
+		err := os.MkdirAll(agentsDir, 0755)
+		if err != nil {
+			return err
+		}
+		PrintAndLog(fmt.Sprintf("created agents directory %s", agentsDir), INFO)
+	}
+
 	for slug := range repositoryMap {
 
 		repositoryDir := filepath.Join(workDirPath, slug)
-		exportDir := filepath.Join(repositoryDir, "exports")
 		unpublishedDir := filepath.Join(repositoryDir, "unpublished")
 
-		err := os.Mkdir(repositoryDir, 0755)
+		err := os.MkdirAll(repositoryDir, 0755)
 		if err != nil {
 			return err
 		}
 		PrintAndLog(fmt.Sprintf("created repository directory %s", repositoryDir), INFO)
 
-		//create the repository export directory
-		err = os.Mkdir(exportDir, 0755)
-		if err != nil {
-			return err
+		//create the repository accessions directory
+		if options.Target == AccessionsTarget {
+			accessionsDir := filepath.Join(repositoryDir, AccessionsTarget)
+			err = os.MkdirAll(accessionsDir, 0755)
+			if err != nil {
+				return err
+			}
+			PrintAndLog(fmt.Sprintf("created accessions directory %s", accessionsDir), INFO)
+			continue
+		}
+
+		//create the repository export directory for each format
+		for _, format := range options.Formats {
+			exportDir := filepath.Join(repositoryDir, options.Directory(format))
+			err = os.MkdirAll(exportDir, 0755)
+			if err != nil {
+				return err
+			}
+			PrintAndLog(fmt.Sprintf("created export directory %s", exportDir), INFO)
 		}
-		PrintAndLog(fmt.Sprintf("created export directory %s", exportDir), INFO)
 
 		if unpublishedResources == true {
-			err = os.Mkdir(unpublishedDir, 0755)
+			err = os.MkdirAll(unpublishedDir, 0755)
 			if err != nil {
 				return err
 			}
 			PrintAndLog(fmt.Sprintf("created export directory %s", unpublishedDir), INFO)
 		}
+
+		if validate == true {
+			for _, format := range options.Formats {
+				if !CanValidate([]ExportFormat{format}) {
+					continue
+				}
+				invalidDir := filepath.Join(repositoryDir, options.InvalidDirectory(format))
+				err = os.MkdirAll(invalidDir, 0755)
+				if err != nil {
+					return err
+				}
+				PrintAndLog(fmt.Sprintf("created invalid directory %s", invalidDir), INFO)
+			}
+		}
 	}
 
 	return nil
@@ -209,21 +356,5 @@ func Cleanup(workDir string) error {
 		return err
 	}
 
-	//move the logfile to the workdir
-	newLoc := filepath.Join(workDir, logfile)
-	err = os.Rename(logfile, newLoc)
-	if err != nil {
-		return err
-	}
-	PrintAndLog(fmt.Sprintf("moved log file to %s", newLoc), INFO)
-
-	//move the reportFile to the workdir
-	newLoc = filepath.Join(workDir, reportFile)
-	err = os.Rename(reportFile, newLoc)
-	if err != nil {
-		return err
-	}
-	PrintAndLog(fmt.Sprintf("moved report file to %s", newLoc), INFO)
-
 	return nil
 }
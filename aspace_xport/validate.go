@@ -0,0 +1,128 @@
+package aspace_xport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ead2002SchemaURL = "https://www.loc.gov/ead/ead.xsd"
+	ead3SchemaURL    = "https://www.loc.gov/ead/ead3.xsd"
+)
+
+const schemaDirectory = "schemas"
+
+// the downloaded schemas and the paths to their local copies in the work directory, by format
+var (
+	schemaBytes = map[ExportFormat][]byte{}
+	eadSchemas  = map[ExportFormat]string{}
+)
+
+// the schema names and urls for the formats that can be validated
+var schemaURLs = map[ExportFormat][2]string{
+	EAD:  {"ead 2002", ead2002SchemaURL},
+	EAD3: {"ead3", ead3SchemaURL},
+}
+
+// true if any of the formats can be validated
+func CanValidate(formats []ExportFormat) bool {
+	for _, format := range formats {
+		if _, ok := schemaURLs[format]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// download the ead 2002 schema for the ead format and the ead3 schema for the ead3 format, they are written to the
+// work directory for xmllint when the export starts
+func LoadSchema(formats []ExportFormat) error {
+	//check that xmllint is available
+	if _, err := exec.LookPath("xmllint"); err != nil {
+		return fmt.Errorf("validation requires xmllint to be installed and on the PATH: %s", err.Error())
+	}
+
+	for _, format := range formats {
+		schema, ok := schemaURLs[format]
+		if !ok {
+			continue
+		}
+		err := loadSchema(format, schema[0], schema[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadSchema(format ExportFormat, schemaName string, schemaURL string) error {
+	response, err := http.Get(schemaURL)
+	if err != nil {
+		return fmt.Errorf("could not retrieve %s schema from %s: %s", schemaName, schemaURL, err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not retrieve %s schema from %s: %s", schemaName, schemaURL, response.Status)
+	}
+
+	schemaBytes[format], err = io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	PrintAndLog(fmt.Sprintf("%s schema loaded from %s", schemaName, schemaURL), INFO)
+	return nil
+}
+
+// write the loaded schemas to a schemas directory in the work directory so xmllint can validate against a local copy
+func writeSchemas(workDir string) error {
+	schemaDir := filepath.Join(workDir, schemaDirectory)
+	err := os.MkdirAll(schemaDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for format, schema := range schemaBytes {
+		schemaPath := filepath.Join(schemaDir, filepath.Base(schemaURLs[format][1]))
+		err = os.WriteFile(schemaPath, schema, 0644)
+		if err != nil {
+			return err
+		}
+		eadSchemas[format] = schemaPath
+	}
+	return nil
+}
+
+// remove the schemas directory from the work directory once the export is done
+func removeSchemas(workDir string) error {
+	return os.RemoveAll(filepath.Join(workDir, schemaDirectory))
+}
+
+// validate an ead against the schema loaded for its format, the returned error contains the validator messages
+func validateEAD(format ExportFormat, eadBytes []byte) error {
+	cmd := exec.Command("xmllint", "--noout", "--schema", eadSchemas[format], "-")
+	cmd.Stdin = bytes.NewReader(eadBytes)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return errors.New(msg)
+	}
+
+	return nil
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	export "github.com/nyudlts/aspace-export/aspace_xport"
@@ -15,20 +19,39 @@ const appVersion = "v1.1.1"
 
 var (
 	config               string
+	consoleLogLevel      string
 	debug                bool
 	environment          string
 	exportLoc            string
+	fileLogLevel         string
+	failOn               string
 	formattedTime        string
 	format               string
 	help                 bool
+	logFile              string
+	logFormat            string
+	logLevel             string
+	maxAttempts          int
+	minFreeSpace         int
+	quiet                bool
+	modifiedSince        string
 	reformat             bool
 	repository           int
 	resource             int
+	resume               string
 	resourceInfo         []export.ResourceInfo
+	retryDelay           int
+	retryJitter          float64
+	retryOn              string
 	startTime            time.Time
 	timeout              int
 	unpublishedNotes     bool
 	unpublishedResources bool
+	agents               bool
+	digitalObjects       string
+	target               string
+	accessionsCSV        bool
+	validate             bool
 	version              bool
 	workDir              string
 	workers              int
@@ -42,13 +65,32 @@ func init() {
 	flag.IntVar(&timeout, "timeout", 20, "client timeout")
 	flag.IntVar(&workers, "workers", 8, "number of concurrent workers")
 	flag.StringVar(&exportLoc, "export-location", ".", "location to export finding aids")
+	flag.IntVar(&minFreeSpace, "min-free-space", 100, "minimum free space in MB required at the export location")
 	flag.BoolVar(&help, "help", false, "display the help message")
 	flag.BoolVar(&version, "version", false, "display the version of the tool and go-aspace library")
 	flag.BoolVar(&reformat, "reformat", false, "tab reformat the output file")
-	flag.StringVar(&format, "format", "", "format of export: ead or marc")
+	flag.StringVar(&format, "format", "", "format of export: ead, ead3, marc, mods, dc, pdf, json or containers, or a comma separated list of formats, json only for accessions")
+	flag.StringVar(&target, "target", "resources", "the records to export: resources or accessions")
+	flag.BoolVar(&accessionsCSV, "accessions-csv", false, "write a csv summary of exported accessions")
 	flag.BoolVar(&unpublishedNotes, "include-unpublished-notes", false, "include unpublished notes")
 	flag.BoolVar(&unpublishedResources, "include-unpublished-resources", false, "include unpublished resources")
-	flag.BoolVar(&debug, "debug", false, "")
+	flag.BoolVar(&agents, "agents", false, "export eac-cpf for the agents linked from exported resources")
+	flag.StringVar(&digitalObjects, "digital-objects", "", "export linked digital objects in a comma separated list of formats: mets, mods or dc")
+	flag.StringVar(&failOn, "fail-on", "error", "exit with a non-zero status on `none`, any `error`, any `warning` or more than a percentage of errors, e.g. `5%`")
+	flag.StringVar(&resume, "resume", "", "path to the work directory of an interrupted export to resume")
+	flag.StringVar(&modifiedSince, "modified-since", "", "only export resources modified since a timestamp or `last-run`")
+	flag.IntVar(&maxAttempts, "max-attempts", 3, "maximum number of attempts for each ArchivesSpace request")
+	flag.IntVar(&retryDelay, "retry-delay", 1000, "base delay in milliseconds before retrying a failed request, doubled on each attempt")
+	flag.Float64Var(&retryJitter, "retry-jitter", 0.2, "fraction of random jitter applied to the retry delay, between 0 and 1")
+	flag.StringVar(&retryOn, "retry-on", "5xx,429,connection", "comma separated list of errors to retry: 5xx, 429, connection, which includes timeouts")
+	flag.BoolVar(&validate, "validate", false, "validate exported finding aids against the ead2002 or ead3 schema")
+	flag.StringVar(&logFile, "log-file", "", "location of the log file until the work directory is created, defaults to the temp directory")
+	flag.StringVar(&logFormat, "log-format", "text", "format of the log file: text or json")
+	flag.StringVar(&logLevel, "log-level", "INFO", "minimum level of messages to print and log: DEBUG, INFO, WARNING or ERROR")
+	flag.StringVar(&consoleLogLevel, "console-log-level", "", "minimum level of messages printed to the console, overrides --log-level")
+	flag.StringVar(&fileLogLevel, "file-log-level", "", "minimum level of messages written to the log file, overrides --log-level")
+	flag.BoolVar(&quiet, "quiet", false, "only print the final report and fatal errors to the console")
+	flag.BoolVar(&debug, "debug", false, "print and log debug messages, same as --log-level DEBUG")
 }
 
 func printHelp() {
@@ -56,18 +98,67 @@ func printHelp() {
 	fmt.Println("options:")
 	fmt.Println("  --config           path/to/the go-aspace configuration file					mandatory")
 	fmt.Println("  --environment      environment key in config file of the instance to run export against   	mandatory")
-	fmt.Println("  --format           the export format either `ead` or `marc`					mandatory")
+	fmt.Println("  --format           the export format `ead`, `ead3`, `marc`, `mods`, `dc`, `pdf`, `json`	mandatory")
+	fmt.Println("                     or `containers`, or a comma separated list, e.g. `ead,marc`, only `json` for accessions")
+	fmt.Println("  --target           the records to export, `resources` or `accessions`			default `resources`")
+	fmt.Println("  --accessions-csv   write a csv summary of exported accessions				default `false`")
 	fmt.Println("  --export-location  path/to/the location to export finding aids                            	default `.`")
+	fmt.Println("  --min-free-space   minimum free space in MB required at the export location			default `100`")
 	fmt.Println("  --include-unpublished-notes		include unpublished notes in exports			default `false`")
 	fmt.Println("  --include-unpublished-resources	include unpublished resources in exports		default `false`")
+	fmt.Println("  --agents           export eac-cpf for agents linked from exported resources		default `false`")
+	fmt.Println("  --digital-objects  export linked digital objects as `mets`, `mods` and/or `dc`		default none")
 	fmt.Println("  --reformat         tab reformat ead xml files							default `false`")
+	fmt.Println("  --fail-on          exit non-zero on `none`, any `error`, any `warning` or a percent of errors e.g. `5%`	default `error`")
+	fmt.Println("  --resume           path/to/the aspace-exports-[timestamp] directory of an interrupted run to resume")
+	fmt.Println("  --modified-since   only export resources modified since YYYY-MM-DD, an RFC3339 time or `last-run`")
+	fmt.Println("  --max-attempts     maximum number of attempts for each ArchivesSpace request			default `3`")
+	fmt.Println("  --retry-delay      base delay in milliseconds before retrying, doubled on each attempt	default `1000`")
+	fmt.Println("  --retry-jitter     fraction of random jitter applied to the retry delay			default `0.2`")
+	fmt.Println("  --retry-on         errors to retry: 5xx, 429, connection (includes timeouts)		default `5xx,429,connection`")
 	fmt.Println("  --repository       ID of the repository to be exported, `0` will export all repositories	default `0` ")
 	fmt.Println("  --resource         ID of the resource to be exported, `0` will export all resources		default `0` ")
 	fmt.Println("  --timeout          client timout in seconds							default `20`")
 	fmt.Println("  --workers          number of concurrent export workers to create				default `8`")
-	fmt.Println("  --validate         validate exported finding aids against ead2002 schema			default `false`")
-	fmt.Println("  --debug	     print debug messages							default `false`")
-	fmt.Println("  --version          print the version and version of client version\n")
+	fmt.Println("  --validate         validate exported finding aids against ead2002 or ead3 schema		default `false`")
+	fmt.Println("  --log-file         path/to/the log file used until the work directory is created		default temp directory")
+	fmt.Println("  --log-format       format of the log file, `text` or `json` lines				default `text`")
+	fmt.Println("  --log-level        minimum level to print and log: DEBUG, INFO, WARNING or ERROR		default `INFO`")
+	fmt.Println("  --console-log-level  minimum level printed to the console, overrides --log-level")
+	fmt.Println("  --file-log-level   minimum level written to the log file, overrides --log-level")
+	fmt.Println("  --quiet            only print the final report and fatal errors				default `false`")
+	fmt.Println("  --debug	     print debug messages, same as --log-level DEBUG				default `false`")
+	fmt.Println("  --version          print the version and version of client version")
+	fmt.Println()
+}
+
+// resolve the console and log file levels from --log-level, --console-log-level, --file-log-level, --debug and --quiet
+func setLogLevels() error {
+	if debug == true {
+		logLevel = "DEBUG"
+	}
+
+	level, err := export.GetLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	console, file := level, level
+	if consoleLogLevel != "" {
+		console, err = export.GetLogLevel(consoleLogLevel)
+		if err != nil {
+			return err
+		}
+	}
+	if fileLogLevel != "" {
+		file, err = export.GetLogLevel(fileLogLevel)
+		if err != nil {
+			return err
+		}
+	}
+
+	export.SetLogLevels(console, file, quiet)
+	return nil
 }
 
 func main() {
@@ -93,11 +184,26 @@ func main() {
 	startTime = time.Now()
 	formattedTime = startTime.Format("20060102-050403")
 
+	//set the console and log file levels
+	err := setLogLevels()
+	if err != nil {
+		export.PrintOnly(err.Error(), export.ERROR)
+		printHelp()
+		os.Exit(1)
+	}
+
 	//starting the application
 	export.PrintOnly(fmt.Sprintf("aspace-export %s", appVersion), export.INFO)
 
 	//create logger
-	err := export.CreateLogger(debug)
+	xportLogFormat, err := export.GetLogFormat(logFormat)
+	if err != nil {
+		export.PrintOnly(err.Error(), export.FATAL)
+		printHelp()
+		os.Exit(1)
+	}
+
+	err = export.CreateLogger(logFile, xportLogFormat)
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.ERROR)
 		printHelp()
@@ -105,8 +211,24 @@ func main() {
 	}
 	export.LogOnly(fmt.Sprintf("aspace-export %s", appVersion), export.INFO)
 
+	//cancel the run on SIGINT or SIGTERM, a second signal exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		export.PrintAndLog(fmt.Sprintf("received %v, finishing in-flight exports, send again to exit immediately", sig), export.WARNING)
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+		cancel()
+	}()
+
+	//accessions are exported as json
+	if target == export.AccessionsTarget && format == "" {
+		format = "json"
+	}
+
 	//check critical flags
-	err = export.CheckFlags(config, environment, format, resource, repository)
+	err = export.CheckFlags(config, environment, format, resource, repository, target, agents, digitalObjects, unpublishedResources)
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.FATAL)
 		err = export.CloseLogger()
@@ -119,15 +241,73 @@ func main() {
 
 	export.PrintAndLog("all mandatory options set", export.INFO)
 
+	//Validate the export formats
+	xportFormats, err := export.GetExportFormats(format)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		os.Exit(9)
+	}
+	format = export.FormatNames(xportFormats)
+
+	//the last run of accessions is recorded separately from resources
+	stateFormat := format
+	if target == export.AccessionsTarget {
+		stateFormat = export.AccessionsTarget
+	}
+
+	//Validate the digital object formats
+	doFormats, err := export.ParseDigitalObjectFormats(digitalObjects)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		printHelp()
+		os.Exit(2)
+	}
+
+	//parse the failure threshold
+	failThreshold, err := export.ParseFailOn(failOn)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		printHelp()
+		os.Exit(2)
+	}
+
+	//create the retry policy
+	retryPolicy, err := export.NewRetryPolicy(maxAttempts, retryDelay, retryJitter, retryOn)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		printHelp()
+		os.Exit(2)
+	}
+
 	//get the absolute path of the export location
-	workDir, err = filepath.Abs(workDir)
+	exportLocation, err := filepath.Abs(exportLoc)
 	if err != nil {
-		export.PrintAndLog(err.Error(), export.ERROR)
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
 		os.Exit(3)
 	}
 
-	//check that export location exists
-	err = export.CheckPath(workDir)
+	//check that export location exists, is writable and has enough free space
+	err = export.CheckExportLocation(exportLocation, minFreeSpace)
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.FATAL)
 		err = export.CloseLogger()
@@ -136,8 +316,52 @@ func main() {
 		}
 		os.Exit(3)
 	}
-	abs, _ := filepath.Abs(workDir)
-	export.PrintAndLog(fmt.Sprintf("%s exists and is a directory", abs), export.INFO)
+	export.PrintAndLog(fmt.Sprintf("%s exists, is a writable directory and has at least %d MB free", exportLocation, minFreeSpace), export.INFO)
+
+	//load the state file and determine which resources to export
+	state, err := export.LoadState(exportLocation)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		os.Exit(12)
+	}
+
+	since, err := export.GetModifiedSince(modifiedSince, state, environment, repository, stateFormat)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		printHelp()
+		os.Exit(2)
+	}
+	if !since.IsZero() {
+		export.PrintAndLog(fmt.Sprintf("exporting resources modified since %s", since.Format(time.RFC3339)), export.INFO)
+	}
+
+	//load the ead schema if validation is set
+	if validate == true {
+		if !export.CanValidate(xportFormats) {
+			export.PrintAndLog("the --validate option only applies to ead and ead3 exports, ignoring", export.WARNING)
+			validate = false
+		} else {
+			err = export.LoadSchema(xportFormats)
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.FATAL)
+				err = export.CloseLogger()
+				if err != nil {
+					export.PrintAndLog(err.Error(), export.ERROR)
+				}
+				os.Exit(11)
+			}
+		}
+	}
+
+	exitIfCancelled(ctx)
 
 	//get a go-aspace api client
 	err = export.CreateAspaceClient(config, environment, timeout)
@@ -151,6 +375,7 @@ func main() {
 	} else {
 		export.PrintAndLog(fmt.Sprintf("go-aspace client created, using go-aspace %s", aspace.LibraryVersion), export.INFO)
 	}
+	exitIfCancelled(ctx)
 
 	//get a map of repositories to be exported
 	repositoryMap, err := export.GetRepositoryMap(repository, environment)
@@ -163,9 +388,14 @@ func main() {
 		os.Exit(5)
 	}
 	export.PrintAndLog(fmt.Sprintf("%d repositories returned from ArchivesSpace", len(repositoryMap)), export.INFO)
+	exitIfCancelled(ctx)
 
 	//get a slice of resourceInfo
-	resourceInfo, err = export.GetResourceIDs(repositoryMap, resource)
+	if target == export.AccessionsTarget {
+		resourceInfo, err = export.GetAccessionIDs(repositoryMap, since)
+	} else {
+		resourceInfo, err = export.GetResourceIDs(repositoryMap, resource, since)
+	}
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.FATAL)
 		err = export.CloseLogger()
@@ -174,56 +404,120 @@ func main() {
 		}
 		os.Exit(6)
 	}
-	export.PrintAndLog(fmt.Sprintf("%d resources returned from ArchivesSpace", len(resourceInfo)), export.INFO)
+	export.PrintAndLog(fmt.Sprintf("%d %s returned from ArchivesSpace", len(resourceInfo), target), export.INFO)
+	exitIfCancelled(ctx)
 
-	//create work directory
-	workDir = filepath.Join(workDir, fmt.Sprintf("aspace-exports-%s", formattedTime))
-	err = export.CreateWorkDirectory(workDir)
-	if err != nil {
-		export.PrintAndLog(err.Error(), export.FATAL)
-		err = export.CloseLogger()
+	if resume != "" {
+		//reuse the work directory of the interrupted run
+		workDir, err = filepath.Abs(resume)
+		if err == nil {
+			err = export.CheckExportLocation(workDir, minFreeSpace)
+		}
 		if err != nil {
-			export.PrintAndLog(err.Error(), export.ERROR)
+			export.PrintAndLog(err.Error(), export.FATAL)
+			err = export.CloseLogger()
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.ERROR)
+			}
+			os.Exit(13)
 		}
-		os.Exit(7)
-	}
-	export.PrintAndLog(fmt.Sprintf("working directory created at %s", workDir), export.INFO)
 
-	//Create the repository export and failure directories
-	err = export.CreateExportDirectories(workDir, repositoryMap, unpublishedResources)
-	if err != nil {
-		export.PrintAndLog(err.Error(), export.FATAL)
-		err = export.CloseLogger()
+		//keep the timestamp of the original run for output filenames
+		if ts, ok := strings.CutPrefix(filepath.Base(workDir), "aspace-exports-"); ok {
+			formattedTime = ts
+		}
+
+		numCompleted, err := export.LoadJournal(workDir)
 		if err != nil {
-			export.PrintAndLog(err.Error(), export.ERROR)
+			export.PrintAndLog(err.Error(), export.FATAL)
+			err = export.CloseLogger()
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.ERROR)
+			}
+			os.Exit(13)
 		}
-		os.Exit(8)
+		export.PrintAndLog(fmt.Sprintf("resuming export in %s, %d resources already completed", workDir, numCompleted), export.INFO)
+	} else {
+		//create work directory
+		workDir = filepath.Join(exportLocation, fmt.Sprintf("aspace-exports-%s", formattedTime))
+		err = export.CreateWorkDirectory(workDir)
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.FATAL)
+			err = export.CloseLogger()
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.ERROR)
+			}
+			os.Exit(7)
+		}
+		export.PrintAndLog(fmt.Sprintf("working directory created at %s", workDir), export.INFO)
 	}
 
-	//Validate the export format
-	xportFormat, err := export.GetExportFormat(format)
+	//reopen the log in the work directory
+	err = export.MoveLogger(workDir)
 	if err != nil {
-		export.PrintAndLog(err.Error(), export.FATAL)
-		err = export.CloseLogger()
-		if err != nil {
-			export.PrintAndLog(err.Error(), export.ERROR)
-		}
-		os.Exit(9)
+		export.PrintAndLog(fmt.Sprintf("could not move the log file to the work directory: %s", err.Error()), export.WARNING)
 	}
 
 	//create ExportOptions struct
 	xportOptions := export.ExportOptions{
+		AppVersion:           appVersion,
+		Environment:          environment,
 		WorkDir:              workDir,
-		Format:               xportFormat,
+		Formats:              xportFormats,
 		UnpublishedNotes:     unpublishedNotes,
 		UnpublishedResources: unpublishedResources,
+		Target:               target,
+		Repository:           repository,
+		Resource:             resource,
+		ModifiedSince:        since,
 		Workers:              workers,
 		Reformat:             reformat,
+		Validate:             validate,
+		Retry:                retryPolicy,
+		Agents:               agents,
+		DigitalObjects:       doFormats,
+		AccessionsCSV:        accessionsCSV,
+	}
+
+	//a resumed run must write the same output as the run it continues, a new run records its options for a later resume
+	if resume != "" {
+		err = export.CheckJournalOptions(xportOptions)
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.FATAL)
+			err = export.CloseLogger()
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.ERROR)
+			}
+			os.Exit(13)
+		}
+	} else {
+		err = export.WriteJournalOptions(xportOptions)
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.FATAL)
+			err = export.CloseLogger()
+			if err != nil {
+				export.PrintAndLog(err.Error(), export.ERROR)
+			}
+			os.Exit(7)
+		}
+	}
+
+	//Create the repository export and failure directories
+	err = export.CreateExportDirectories(workDir, repositoryMap, xportOptions)
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.FATAL)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		os.Exit(8)
 	}
 
+	exitIfCancelled(ctx)
+
 	//export resources
 	export.PrintAndLog(fmt.Sprintf("processing %d resources", len(resourceInfo)), export.INFO)
-	err = export.ExportResources(xportOptions, startTime, formattedTime, &resourceInfo)
+	err = export.ExportResources(ctx, xportOptions, startTime, formattedTime, &resourceInfo)
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.FATAL)
 		err = export.CloseLogger()
@@ -233,6 +527,25 @@ func main() {
 		os.Exit(10)
 	}
 
+	//record the run time for future --modified-since=last-run exports if every resource exported
+	//a resumed run started before this process so its run time is not known and the state is left as is
+	cancelled := ctx.Err() != nil
+	if cancelled {
+		export.PrintAndLog("run was cancelled, the last run time was not updated", export.INFO)
+	} else if resume != "" {
+		export.PrintAndLog("resumed run, the last run time was not updated", export.INFO)
+	} else if resource != 0 {
+		//the state is kept for whole repositories, a single resource run would hide changes to the other resources
+		export.PrintAndLog("single resource run, the last run time was not updated", export.INFO)
+	} else if export.Summary().Errors == 0 {
+		err = export.SaveState(exportLocation, state, environment, repository, stateFormat, startTime)
+		if err != nil {
+			export.PrintAndLog(fmt.Sprintf("could not write state file: %s", err.Error()), export.WARNING)
+		}
+	} else {
+		export.PrintAndLog("errors were encountered, the last run time was not updated", export.WARNING)
+	}
+
 	//clean up directories
 	err = export.Cleanup(workDir)
 	if err != nil {
@@ -240,11 +553,45 @@ func main() {
 	}
 
 	//exit
-	export.PrintAndLog("aspace-export process complete, exiting\n", export.INFO)
+	if cancelled {
+		export.PrintAndLog(fmt.Sprintf("aspace-export cancelled, resume with --resume %s\n", workDir), export.WARNING)
+		err = export.CloseLogger()
+		if err != nil {
+			export.PrintAndLog(err.Error(), export.ERROR)
+		}
+		os.Exit(14)
+	}
+
+	//exit with a non-zero status if the results cross the --fail-on threshold
+	exitCode := 0
+	failedErrors, failedWarnings := failThreshold.Check(export.Summary())
+	if failedErrors {
+		export.PrintAndLog("aspace-export process complete with errors, exiting\n", export.ERROR)
+		exitCode = 15
+	} else if failedWarnings {
+		export.PrintAndLog("aspace-export process complete with warnings, exiting\n", export.WARNING)
+		exitCode = 16
+	} else {
+		export.PrintAndLog("aspace-export process complete, exiting\n", export.INFO)
+	}
+
 	err = export.CloseLogger()
 	if err != nil {
 		export.PrintAndLog(err.Error(), export.ERROR)
 	}
 
-	os.Exit(0)
+	os.Exit(exitCode)
+}
+
+// exit if the run was cancelled while it was being set up, before any records were exported
+func exitIfCancelled(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	export.PrintAndLog("aspace-export cancelled before the export started", export.WARNING)
+	err := export.CloseLogger()
+	if err != nil {
+		export.PrintAndLog(err.Error(), export.ERROR)
+	}
+	os.Exit(14)
 }